@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
@@ -19,6 +20,7 @@ import (
 	"effective-mobile-task/internal/client"
 	"effective-mobile-task/internal/config"
 	"effective-mobile-task/internal/handler"
+	"effective-mobile-task/internal/jobs"
 	"effective-mobile-task/internal/repository"
 	"effective-mobile-task/internal/service"
 )
@@ -26,9 +28,15 @@ import (
 // @title People Enrichment API
 // @version 1.0
 // @description Сервис для обогащения ФИО возрастом, полом и национальностью
+// @description Ошибки возвращаются в формате application/problem+json (RFC 7807): type определяет вид ошибки, errors - некорректные поля, request_id совпадает с X-Request-ID.
 // @host localhost:8080
 // @BasePath /
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Bearer-токен администратора из ADMIN_TOKEN
+
 // @contact.name API Support
 // @contact.email support@example.com
 func main() {
@@ -42,34 +50,128 @@ func main() {
 
 	logger.Info("Starting server", zap.String("host", cfg.ServerHost), zap.String("port", cfg.ServerPort))
 
-	dsn := "host=" + cfg.DBHost + " port=" + cfg.DBPort + " user=" + cfg.DBUser +
-		" password=" + cfg.DBPassword + " dbname=" + cfg.DBName + " sslmode=" + cfg.DBSSLMode
+	txOptions, err := repository.ParseTxOptions(cfg.TxIsolation, cfg.TxMaxRetries)
+	if err != nil {
+		logger.Fatal("Invalid transaction config", zap.Error(err))
+	}
+
+	var repo repository.PersonStore
+	var importRepo repository.ImportStore
+	var idempotencyRepo repository.IdempotencyStore
+	var transactions repository.UnitOfWork
+	switch cfg.Storage {
+	case "memory":
+		logger.Warn("Using in-memory storage, data will be lost on restart")
+		memoryPersons := repository.NewMemoryPersonRepository(cfg.UniquePersonNames)
+		memoryImports := repository.NewMemoryImportRepository()
+		memoryIdempotency := repository.NewMemoryIdempotencyRepository()
+		repo, importRepo, idempotencyRepo = memoryPersons, memoryImports, memoryIdempotency
+		transactions = repository.NewMemoryUnitOfWork(memoryPersons, memoryImports, memoryIdempotency)
+	case "postgres":
+		dsn := "host=" + cfg.DBHost + " port=" + cfg.DBPort + " user=" + cfg.DBUser +
+			" password=" + cfg.DBPassword + " dbname=" + cfg.DBName + " sslmode=" + cfg.DBSSLMode
+
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			logger.Fatal("Cannot connect to DB", zap.Error(err))
+		}
+		defer db.Close()
+
+		repo = repository.NewPersonRepository(db)
+		importRepo = repository.NewImportRepository(db)
+		idempotencyRepo = repository.NewIdempotencyRepository(db)
+		transactions = repository.NewTxManager(db, txOptions)
+	default:
+		logger.Fatal("Unknown storage", zap.String("storage", cfg.Storage))
+	}
+
+	httpEnricher := client.NewEnrichmentClient(cfg.GenderizeAPIURL, cfg.AgifyAPIURL, cfg.NationalizeAPIURL)
+	httpEnricher.CountryHint = cfg.EnrichmentCountryHint
+	if cfg.EnrichmentCacheSoftTTL > 0 {
+		httpEnricher.Cache = client.NewCache(cfg.EnrichmentCacheSoftTTL, cfg.EnrichmentCacheHardTTL, cfg.EnrichmentCacheSize)
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	strategy, err := client.ParseStrategy(cfg.EnrichmentStrategy)
 	if err != nil {
-		logger.Fatal("Cannot connect to DB", zap.Error(err))
+		logger.Fatal("Invalid enrichment config", zap.Error(err))
+	}
+	sources := []client.Source{{Provider: httpEnricher, Weight: cfg.EnrichmentHTTPWeight}}
+	if cfg.EnrichmentDatasetPath != "" {
+		dataset, err := client.LoadDatasetProvider(cfg.EnrichmentDatasetPath)
+		if err != nil {
+			logger.Fatal("Cannot load enrichment dataset", zap.Error(err))
+		}
+		sources = append(sources, client.Source{Provider: dataset, Weight: cfg.EnrichmentDatasetWeight})
+	}
+	if cfg.EnrichmentPatronymicWeight > 0 {
+		sources = append(sources, client.Source{Provider: client.NewPatronymicProvider(), Weight: cfg.EnrichmentPatronymicWeight})
 	}
-	defer db.Close()
+	enricher := client.NewComposite(strategy, sources...)
 
-	enricher := client.NewEnrichmentClient(cfg.GenderizeAPIURL, cfg.AgifyAPIURL, cfg.NationalizeAPIURL)
-	repo := repository.NewPersonRepository(db)
-	service := service.NewPersonService(repo, enricher)
-	handler := handler.NewPersonHandler(service, logger)
+	duplicates, err := service.ParseDuplicatePolicy(cfg.DuplicateDetection, cfg.DuplicateThreshold)
+	if err != nil {
+		logger.Fatal("Invalid duplicate detection config", zap.Error(err))
+	}
 
 	r := mux.NewRouter()
-	r.HandleFunc("/persons", handler.Create).Methods("POST")
+	r.Use(handler.Actor)
+	r.NotFoundHandler = http.HandlerFunc(handler.NotFound)
+	r.MethodNotAllowedHandler = handler.MethodNotAllowed(r)
+	// снаружи роутера, чтобы идентификатор был и у ответов NotFound/MethodNotAllowed
+	root := handler.RequestID(logger)(r)
+
+	personService := service.NewPersonService(repo, transactions, enricher)
+	personService.Duplicates = duplicates
+	importService := service.NewImportService(importRepo, personService)
+	importHandler := handler.NewImportHandler(importService, logger)
+	if err := service.CheckIdempotencyLockTimeout(cfg.IdempotencyLockTimeout, cfg.ServerWriteTimeout); err != nil {
+		logger.Fatal("Invalid idempotency config", zap.Error(err))
+	}
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.IdempotencyTTL, cfg.IdempotencyLockTimeout)
+	idempotent := handler.Idempotency(idempotencyService, logger)
+	handler := handler.NewPersonHandler(personService, logger, handler.Options{
+		RequireIfMatch:     cfg.RequireIfMatch,
+		AdminToken:         cfg.AdminToken,
+		ExportWriteTimeout: cfg.ExportWriteTimeout,
+	})
+
+	r.Handle("/persons", idempotent(http.HandlerFunc(handler.Create))).Methods("POST")
 	r.HandleFunc("/persons", handler.List).Methods("GET")
+	r.HandleFunc("/persons/search", handler.Search).Methods("GET")
+	r.Handle("/persons/bulk", idempotent(http.HandlerFunc(handler.Bulk))).Methods("POST")
+	r.HandleFunc("/persons/export", handler.Export).Methods("GET")
+	r.HandleFunc("/persons/duplicates", handler.Duplicates).Methods("GET")
+	r.HandleFunc("/persons/merge", handler.Merge).Methods("POST")
 	r.HandleFunc("/persons/{id}", handler.GetByID).Methods("GET")
 	r.HandleFunc("/persons/{id}", handler.Update).Methods("PUT")
+	r.HandleFunc("/persons/{id}", handler.Patch).Methods("PATCH")
 	r.HandleFunc("/persons/{id}", handler.Delete).Methods("DELETE")
+	r.HandleFunc("/persons/{id}/restore", handler.Restore).Methods("POST")
+	r.HandleFunc("/persons/{id}/history", handler.History).Methods("GET")
+
+	r.HandleFunc("/imports", importHandler.Create).Methods("POST")
+	r.HandleFunc("/imports/{id}", importHandler.Get).Methods("GET")
+	r.HandleFunc("/imports/{id}/errors", importHandler.Errors).Methods("GET")
 
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
 	srv := &http.Server{
 		Addr:         cfg.ServerHost + ":" + cfg.ServerPort,
-		Handler:      r,
+		Handler:      root,
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: cfg.ServerWriteTimeout,
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if cfg.PurgeInterval > 0 {
+		go jobs.NewPurgeJob(personService, logger, cfg.PurgeInterval, cfg.PurgeRetention).Run(jobsCtx)
+	}
+	if cfg.IdempotencyCleanupInterval > 0 {
+		go jobs.NewIdempotencyCleanupJob(idempotencyService, logger, cfg.IdempotencyCleanupInterval).Run(jobsCtx)
+	}
+	if cfg.ImportInterval > 0 {
+		go jobs.NewImportJob(importService, logger, cfg.ImportInterval, cfg.ImportLease).Run(jobsCtx)
 	}
 
 	go func() {
@@ -78,14 +180,37 @@ func main() {
 		}
 	}()
 
+	// служебные метрики отдаются на отдельном адресе, а не на публичном API
+	var admin *http.Server
+	if cfg.AdminAddr != "" {
+		expvar.Publish("enrichment", expvar.Func(func() any { return httpEnricher.Stats() }))
+		adminMux := http.NewServeMux()
+		adminMux.Handle("GET /debug/vars", expvar.Handler())
+		admin = &http.Server{
+			Addr:         cfg.AdminAddr,
+			Handler:      adminMux,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		}
+		go func() {
+			if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("Admin server error", zap.Error(err))
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if admin != nil {
+		admin.Shutdown(ctx)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
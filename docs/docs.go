@@ -18,9 +18,193 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/imports": {
+            "post": {
+                "description": "Принимает CSV или NDJSON файлом в multipart/form-data (поле file) или телом запроса.\nФайл проверяется сразу, строки обогащаются и сохраняются в фоне; прогресс - в GET /imports/{id}.\nФормат определяется параметром format, расширением файла или Content-Type.",
+                "consumes": [
+                    "multipart/form-data",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Загрузка файла для импорта людей",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Файл",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Формат",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON: поле человека -\u003e столбец, например {\\",
+                        "name": "mapping",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": ",",
+                        "description": "Разделитель CSV",
+                        "name": "delimiter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Import"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "Адрес импорта"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Состояние импорта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID импорта",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Import"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/imports/{id}/errors": {
+            "get": {
+                "description": "CSV с номером строки исходного файла, исходными столбцами и текстом ошибки",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "imports"
+                ],
+                "summary": "Строки импорта с ошибками",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID импорта",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
         "/persons": {
             "get": {
-                "description": "Получить список людей с фильтрами и пагинацией",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Получить список людей с фильтрами и пагинацией.\nС параметром cursor (пустое значение - первая страница) включается пагинация курсорами.\nОтвет - массив, а с envelope=true или cursor - models.PersonPage.\nОбщее количество и ссылки на соседние страницы также отдаются в X-Total-Count и Link.\nПо умолчанию общее количество - оценка (X-Total-Count-Estimated: true), точный подсчет - count=exact.",
                 "produces": [
                     "application/json"
                 ],
@@ -43,19 +227,56 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Имя",
+                        "description": "Курсор из next_cursor или prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Вернуть models.PersonPage вместо массива",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "exact",
+                            "estimated",
+                            "none"
+                        ],
+                        "type": "string",
+                        "default": "estimated",
+                        "description": "Подсчет общего количества: exact - точно, estimated - оценка планировщика, none - без подсчета",
+                        "name": "count",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "Поля сортировки через запятую, минус - по убыванию: name, surname, patronymic, age, gender, nationality, created_at, updated_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя: [not:][contains|prefix|exact|in:]значения через запятую",
                         "name": "name",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Фамилия",
+                        "description": "Фамилия, синтаксис как у name",
                         "name": "surname",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Возраст",
+                        "type": "string",
+                        "description": "Отчество, синтаксис как у name, а также null:true|false",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Текущий возраст: [not:][eq|in|gt|gte|lt|lte|between:]значения, например between:20,30",
                         "name": "age",
                         "in": "query"
                     },
@@ -73,15 +294,33 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Пол",
+                        "description": "Пол: [not:][eq|in:]значения через запятую",
                         "name": "gender",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Национальность",
+                        "description": "Национальность, синтаксис как у gender",
                         "name": "nationality",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата создания: [not:][eq|gt|gte|lt|lte|between:]RFC 3339 или ГГГГ-ММ-ДД (целые сутки по UTC)",
+                        "name": "created_at",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата изменения, синтаксис как у created_at",
+                        "name": "updated_at",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленных (только администратор)",
+                        "name": "include_deleted",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -92,18 +331,40 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/models.Person"
                             }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Ссылки next и prev"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество записей"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Обогащает ФИО через внешние API и сохраняет в БД",
+                "description": "Обогащает ФИО через внешние API и сохраняет в БД.\nЕсли человек с тем же ФИО уже есть, возвращается 409 с его id в existing_id и заголовке Location,\nа с on_duplicate=return - 200 с существующей записью.\nФИО приводится к каноническому виду (лишние пробелы, Unicode NFC, заглавные буквы с учетом дефисов\nи частиц в фамилии вроде \"de la Cruz\"), присланные значения сохраняются в *_original.\nФИО - буквы латиницы или кириллицы, пробелы, дефисы и апострофы,\nне длиннее 150 символов. Неизвестные поля и все нарушения перечисляются в errors ответа 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -123,34 +384,83 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.CreatePersonRequest"
                         }
+                    },
+                    {
+                        "enum": [
+                            "error",
+                            "return"
+                        ],
+                        "type": "string",
+                        "default": "error",
+                        "description": "Что делать при дубликате",
+                        "name": "on_duplicate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ, а параллельный повтор - 409",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "Существующий дубликат при on_duplicate=return",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
                     "201": {
                         "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/models.Person"
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true, если ответ сохранен для прошлого запроса с тем же ключом"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/persons/{id}": {
-            "put": {
+        "/persons/bulk": {
+            "post": {
+                "description": "Принимает JSON-массив или NDJSON (Content-Type: application/x-ndjson), не больше 1000 элементов.\nВозвращает статус каждого элемента: created, duplicate, validation_error, enrichment_error, skipped.\nДубликат элемента пачки указывается в duplicate_of, уже сохраненного человека - в existing_id.\nВ режиме atomic при любой ошибке не сохраняется никто и ответ 422, в режиме best_effort сохраняются корректные элементы.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson"
                 ],
                 "produces": [
                     "application/json"
@@ -158,80 +468,788 @@ const docTemplate = `{
                 "tags": [
                     "persons"
                 ],
-                "summary": "Обновить данные человека по ID",
+                "summary": "Массовое создание людей",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "UUID человека",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Новые данные",
+                        "description": "Люди",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreatePersonRequest"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.CreatePersonRequest"
+                            }
                         }
+                    },
+                    {
+                        "enum": [
+                            "best_effort",
+                            "atomic"
+                        ],
+                        "type": "string",
+                        "default": "best_effort",
+                        "description": "Режим",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.UpdatePersonRequest"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BulkItemResult"
+                            }
+                        },
+                        "headers": {
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true, если ответ сохранен для прошлого запроса с тем же ключом"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/persons/duplicates": {
+            "get": {
+                "description": "Группы людей с похожими ФИО. Оценка группы учитывает сходство ФИО и совпадение пола, национальности и возраста.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "persons"
                 ],
-                "summary": "Удалить человека по ID",
+                "summary": "Поиск возможных дубликатов",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "UUID человека",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "204": {
-                        "description": "No Content"
+                        "type": "number",
+                        "default": 0.6,
+                        "description": "Минимальное сходство ФИО, от 0 до 1",
+                        "name": "threshold",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Наибольшее число групп",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DuplicateCluster"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Выгружает всех людей под фильтрами списка в CSV, NDJSON или XLSX. Строки передаются потоком по мере чтения из базы.\nФормат задается параметром format или заголовком Accept, по умолчанию CSV.\nФильтры и sort - как в GET /persons.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Выгрузка людей",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "Формат",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id,name,surname,patronymic,age,gender,nationality,created_at,updated_at",
+                        "description": "Столбцы через запятую: id, name, surname, patronymic, name_original, surname_original, patronymic_original, age, birth_year, gender, nationality, created_at, updated_at, version, deleted_at",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-created_at",
+                        "description": "Поля сортировки, как в GET /persons",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя, синтаксис как в GET /persons",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Текущий возраст",
+                        "name": "age",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата создания",
+                        "name": "created_at",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата изменения",
+                        "name": "updated_at",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Включить удаленных (только администратор)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл выгрузки",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/merge": {
+            "post": {
+                "description": "Поглощает merged_ids выжившим survivor_id. Значение каждого поля выбирается правилом из fields,\nпоглощенные удаляются, слияние записывается в историю всех участников,\nа GET /persons/{id} поглощенного перенаправляет на выжившего.\nIf-Match проверяется у выжившего.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Слияние дубликатов",
+                "parameters": [
+                    {
+                        "description": "Участники и правила слияния",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MergePersonsRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag выжившего",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/search": {
+            "get": {
+                "description": "Нечеткий поиск по имени, фамилии и отчеству с учетом опечаток.\nРезультаты отсортированы по релевантности, совпавшие части полей выделены в highlights тегом \u003cem\u003e.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Поиск людей по ФИО",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Лимит",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PersonSearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/{id}": {
+            "put": {
+                "description": "Полная замена: отсутствующее отчество очищается",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Заменить данные человека по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из GET /persons/{id}",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Новые данные",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreatePersonRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Запись помечается удаленной и окончательно удаляется после срока хранения",
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Удалить человека по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из GET /persons/{id}",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "application/merge-patch+json (RFC 7396) или application/json-patch+json (RFC 6902).\nПатч применяется к документу {\"name\", \"surname\", \"patronymic\"}.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Частично обновить данные человека по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag из GET /persons/{id}",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge Patch или массив операций JSON Patch",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdatePersonRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "Снимки до и после каждого изменения, от новых к старым.\nchanged_by - справочное значение из заголовка X-Actor, оно не проверяется и не подтверждает авторство.\nИстория удаленного человека отдается только администратору с include_deleted=true.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "История изменений человека",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Лимит, не больше 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "История удаленного человека (только администратор)",
+                        "name": "include_deleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PersonHistoryEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/persons/{id}/restore": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Восстановить удаленного человека по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -239,23 +1257,215 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.BulkItemResult": {
+            "type": "object",
+            "properties": {
+                "duplicate_of": {
+                    "description": "DuplicateOf - индекс элемента запроса с тем же ФИО",
+                    "type": "integer",
+                    "example": 0
+                },
+                "error": {
+                    "type": "string"
+                },
+                "existing_id": {
+                    "description": "ExistingID - id уже сохраненного человека с тем же ФИО",
+                    "type": "string",
+                    "example": "1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "created"
+                }
+            }
+        },
         "models.CreatePersonRequest": {
             "type": "object",
+            "required": [
+                "name",
+                "surname"
+            ],
             "properties": {
                 "name": {
                     "type": "string",
+                    "maxLength": 150,
                     "example": "Dmitriy"
                 },
                 "patronymic": {
                     "type": "string",
+                    "maxLength": 150,
                     "example": "Vasilevich"
                 },
                 "surname": {
                     "type": "string",
+                    "maxLength": 150,
                     "example": "Ushakov"
                 }
             }
         },
+        "models.DuplicateCluster": {
+            "type": "object",
+            "properties": {
+                "matching_attributes": {
+                    "description": "MatchingAttributes - атрибуты, совпадающие у всех людей группы",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "gender",
+                        "nationality"
+                    ]
+                },
+                "persons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Person"
+                    }
+                },
+                "score": {
+                    "description": "Score - средняя оценка пар группы: сходство ФИО и совпадение атрибутов, от 0 до 1",
+                    "type": "number",
+                    "example": 0.82
+                }
+            }
+        },
+        "models.EnrichmentSource": {
+            "type": "object",
+            "properties": {
+                "attribute": {
+                    "type": "string",
+                    "example": "gender"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "probability": {
+                    "type": "number",
+                    "example": 0.98
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "http"
+                },
+                "selected": {
+                    "type": "boolean"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "male"
+                },
+                "weight": {
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "models.Import": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "columns": {
+                    "description": "Columns - заголовок CSV; для NDJSON - единственный столбец record с исходной строкой",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Фамилия",
+                        "Имя",
+                        "Отчество"
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "hr@example.com"
+                },
+                "created_rows": {
+                    "type": "integer",
+                    "example": 295
+                },
+                "delimiter": {
+                    "type": "string",
+                    "example": ";"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "failed_rows": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "csv"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "6f1c2b1e-8a53-4a5e-9d8c-2a7f3c1e9b10"
+                },
+                "mapping": {
+                    "description": "Mapping - поле человека (name, surname, patronymic) -\u003e столбец CSV или ключ NDJSON",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "processed_rows": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "status": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "total_rows": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MergePersonsRequest": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "Fields - откуда брать значение поля (name, surname, patronymic, age, gender, nationality):\nsurvivor (по умолчанию, пустое дополняется из остальных), newest, oldest, most_common или id одной из записей",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "merged_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "survivor_id": {
+                    "type": "string",
+                    "example": "1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"
+                }
+            }
+        },
         "models.Person": {
             "type": "object",
             "properties": {
@@ -263,9 +1473,22 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 30
                 },
+                "birth_year": {
+                    "type": "integer",
+                    "example": 1995
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "enrichment_sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EnrichmentSource"
+                    }
+                },
                 "gender": {
                     "type": "string",
                     "example": "male"
@@ -278,6 +1501,11 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Dmitriy"
                 },
+                "name_original": {
+                    "description": "ФИО в том виде, в каком его прислал клиент; Name, Surname и Patronymic - канонический вид.",
+                    "type": "string",
+                    "example": " DMITRIY "
+                },
                 "nationality": {
                     "type": "string",
                     "example": "RU"
@@ -286,29 +1514,224 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Vasilevich"
                 },
+                "patronymic_original": {
+                    "type": "string",
+                    "example": "Vasilevich"
+                },
                 "surname": {
                     "type": "string",
                     "example": "Ushakov"
                 },
+                "surname_original": {
+                    "type": "string",
+                    "example": "ushakov"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
                 }
             }
         },
-        "models.UpdatePersonRequest": {
+        "models.PersonHistoryEntry": {
             "type": "object",
             "properties": {
-                "name": {
+                "action": {
+                    "type": "string",
+                    "example": "update"
+                },
+                "after": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "before": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "changed_at": {
                     "type": "string"
                 },
-                "patronymic": {
+                "changed_by": {
+                    "description": "ChangedBy - значение заголовка X-Actor, переданное клиентом; сервер его не проверяет,\nпоэтому поле справочное и не подтверждает авторство",
+                    "type": "string",
+                    "example": "operator@example.com"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "person_id": {
+                    "type": "string",
+                    "example": "1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"
+                }
+            }
+        },
+        "models.PersonSearchResult": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "birth_year": {
+                    "type": "integer",
+                    "example": 1995
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
                     "type": "string"
                 },
+                "enrichment_sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EnrichmentSource"
+                    }
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "male"
+                },
+                "highlights": {
+                    "description": "Highlights - поля с совпавшими частями в \u003cem\u003e",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Dmitriy"
+                },
+                "name_original": {
+                    "description": "ФИО в том виде, в каком его прислал клиент; Name, Surname и Patronymic - канонический вид.",
+                    "type": "string",
+                    "example": " DMITRIY "
+                },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                },
+                "patronymic": {
+                    "type": "string",
+                    "example": "Vasilevich"
+                },
+                "patronymic_original": {
+                    "type": "string",
+                    "example": "Vasilevich"
+                },
+                "score": {
+                    "type": "number",
+                    "example": 0.83
+                },
                 "surname": {
+                    "type": "string",
+                    "example": "Ushakov"
+                },
+                "surname_original": {
+                    "type": "string",
+                    "example": "ushakov"
+                },
+                "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.UpdatePersonRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "surname"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 150
+                },
+                "patronymic": {
+                    "type": "string",
+                    "maxLength": 150
+                },
+                "surname": {
+                    "type": "string",
+                    "maxLength": 150
+                }
+            }
+        },
+        "problem.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "Field - поле тела (name, items[3].surname) или параметр запроса (limit)",
+                    "type": "string",
+                    "example": "name"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "is required"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "validation failed: name is required"
+                },
+                "errors": {
+                    "description": "Errors - ошибки отдельных полей тела или параметров запроса",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/problem.FieldError"
+                    }
+                },
+                "existing_id": {
+                    "description": "ExistingID - id уже существующего человека для type /problems/duplicate",
+                    "type": "string",
+                    "example": "1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"
+                },
+                "instance": {
+                    "description": "Instance - путь запроса",
+                    "type": "string",
+                    "example": "/persons"
+                },
+                "request_id": {
+                    "description": "RequestID - совпадает с заголовком X-Request-ID ответа",
+                    "type": "string",
+                    "example": "5f0c6a8e-2d7b-4f0e-9a41-0b8f6c7e1d23"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 422
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "/problems/validation-error"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Bearer-токен администратора из ADMIN_TOKEN",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -319,7 +1742,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/",
 	Schemes:          []string{},
 	Title:            "People Enrichment API",
-	Description:      "Сервис для обогащения ФИО возрастом, полом и национальностью",
+	Description:      "Сервис для обогащения ФИО возрастом, полом и национальностью\nОшибки возвращаются в формате application/problem+json (RFC 7807): type определяет вид ошибки, errors - некорректные поля, request_id совпадает с X-Request-ID.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,19 @@
+// Package actor передает через context автора изменения для истории.
+package actor
+
+import "context"
+
+const Anonymous = "anonymous"
+
+type ctxKey struct{}
+
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, name)
+}
+
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(ctxKey{}).(string); ok && name != "" {
+		return name
+	}
+	return Anonymous
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type cacheEntry struct {
+	key       string
+	body      []byte
+	fetchedAt time.Time
+}
+
+// Cache хранит ответы внешних API по ключу.
+// Записи старше SoftTTL отдаются сразу, а обновление уходит в фон (stale-while-revalidate).
+// Записи старше HardTTL считаются протухшими и запрашиваются синхронно.
+// Если записей больше maxEntries, вытесняются давно не читавшиеся (LRU).
+type Cache struct {
+	softTTL    time.Duration
+	hardTTL    time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	group   singleflight.Group
+}
+
+// NewCache создает кэш; maxEntries <= 0 снимает ограничение на число записей.
+func NewCache(softTTL, hardTTL time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		softTTL:    softTTL,
+		hardTTL:    hardTTL,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get возвращает значение по ключу. coalesced означает, что вызов дождался
+// загрузки, начатой другим вызовом, и сам load не выполнял.
+func (c *Cache) Get(key string, load func() ([]byte, error)) (body []byte, coalesced bool, err error) {
+	entry, ok := c.lookup(key)
+	if ok {
+		age := time.Since(entry.fetchedAt)
+		if age < c.softTTL {
+			return entry.body, false, nil
+		}
+		if c.hardTTL <= 0 || age < c.hardTTL {
+			go c.fill(key, load)
+			return entry.body, false, nil
+		}
+	}
+
+	return c.fill(key, load)
+}
+
+// Len возвращает число записей в кэше.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *Cache) lookup(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	c.order.MoveToFront(el)
+	return *el.Value.(*cacheEntry), true
+}
+
+func (c *Cache) store(key string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, body: body, fetchedAt: time.Now()}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// fill запрашивает значение через load не более одного раза на ключ одновременно.
+// При ошибке старая запись остается в кэше.
+func (c *Cache) fill(key string, load func() ([]byte, error)) ([]byte, bool, error) {
+	loaded := false
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		loaded = true
+		body, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, body)
+		return body, nil
+	})
+	if err != nil {
+		return nil, !loaded, err
+	}
+	return v.([]byte), !loaded, nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(time.Hour, 0, 2)
+	loads := map[string]int{}
+	load := func(key string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			loads[key]++
+			return []byte(key), nil
+		}
+	}
+
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		body, _, err := c.Get(key, load(key))
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		if string(body) != key {
+			t.Fatalf("Get(%q) = %q", key, body)
+		}
+	}
+
+	// c вытеснил b, а не недавно прочитанный a
+	want := map[string]int{"a": 1, "b": 2, "c": 1}
+	for key, n := range want {
+		if loads[key] != n {
+			t.Errorf("loads[%q] = %d, want %d", key, loads[key], n)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestCacheKeepsEntryOnLoadError(t *testing.T) {
+	c := NewCache(time.Nanosecond, time.Nanosecond, 0)
+	if _, _, err := c.Get("k", func() ([]byte, error) { return []byte("old"), nil }); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	errLoad := errors.New("upstream down")
+	if _, _, err := c.Get("k", func() ([]byte, error) { return nil, errLoad }); !errors.Is(err, errLoad) {
+		t.Fatalf("err = %v, want %v", err, errLoad)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestCacheReportsCoalescedCallers(t *testing.T) {
+	c := NewCache(time.Hour, 0, 0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var loads atomic.Int64
+	load := func() ([]byte, error) {
+		if loads.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return []byte("v"), nil
+	}
+
+	const callers = 5
+	var coalesced atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			if i > 0 {
+				<-started
+			}
+			_, shared, err := c.Get("k", load)
+			if err != nil {
+				t.Error(err)
+			}
+			if shared {
+				coalesced.Add(1)
+			}
+		}()
+	}
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("loads = %d, want 1", loads.Load())
+	}
+	if coalesced.Load() != callers-1 {
+		t.Errorf("coalesced = %d, want %d", coalesced.Load(), callers-1)
+	}
+}
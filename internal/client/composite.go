@@ -0,0 +1,254 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"effective-mobile-task/internal/models"
+)
+
+type Strategy string
+
+const (
+	// StrategyFirstSuccess берет ответ первого по порядку регистрации провайдера, который его дал.
+	StrategyFirstSuccess Strategy = "first_success"
+	// StrategyMajority выбирает значение, за которое проголосовало больше провайдеров.
+	StrategyMajority Strategy = "majority"
+	// StrategyWeighted выбирает значение с наибольшей суммой вес*вероятность, возраст усредняется.
+	StrategyWeighted Strategy = "weighted"
+)
+
+const (
+	attrGender      = "gender"
+	attrAge         = "age"
+	attrNationality = "nationality"
+)
+
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case StrategyFirstSuccess, StrategyMajority, StrategyWeighted:
+		return Strategy(s), nil
+	}
+	return "", fmt.Errorf("unknown enrichment strategy: %s", s)
+}
+
+type Enrichment struct {
+	Age         int
+	Gender      string
+	Nationality string
+	Sources     []models.EnrichmentSource
+}
+
+type Enricher interface {
+	Enrich(q Query) (*Enrichment, error)
+}
+
+// Source - провайдер, реализующий хотя бы один из GenderProvider, AgeProvider, NationalityProvider.
+type Source struct {
+	Provider interface{ Name() string }
+	Weight   float64
+}
+
+// Composite опрашивает все провайдеры параллельно и объединяет ответы по выбранной стратегии.
+type Composite struct {
+	strategy Strategy
+	sources  []Source
+}
+
+func NewComposite(strategy Strategy, sources ...Source) *Composite {
+	return &Composite{strategy: strategy, sources: sources}
+}
+
+type vote struct {
+	present     bool
+	provider    string
+	weight      float64
+	value       string
+	probability float64
+	err         error
+	selected    bool
+}
+
+func (v vote) score() float64 {
+	return v.weight * v.probability
+}
+
+func (c *Composite) Enrich(q Query) (*Enrichment, error) {
+	attrs := []string{attrGender, attrAge, attrNationality}
+	votes := make(map[string][]vote, len(attrs))
+
+	var wg sync.WaitGroup
+	for _, attr := range attrs {
+		votes[attr] = make([]vote, len(c.sources))
+		for i, src := range c.sources {
+			estimate := estimator(src.Provider, attr)
+			if estimate == nil {
+				continue
+			}
+
+			v := &votes[attr][i]
+			v.present = true
+			v.provider = src.Provider.Name()
+			v.weight = src.Weight
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v.value, v.probability, v.err = estimate(q)
+			}()
+		}
+	}
+	wg.Wait()
+
+	result := &Enrichment{Nationality: "unknown"}
+	for _, attr := range attrs {
+		value, err := c.choose(attr, votes[attr])
+		if err != nil {
+			return nil, fmt.Errorf("failed to enrich %s: %w", attr, err)
+		}
+
+		switch attr {
+		case attrGender:
+			if value != "" {
+				result.Gender = value
+			}
+		case attrAge:
+			if value != "" {
+				result.Age, _ = strconv.Atoi(value)
+			}
+		case attrNationality:
+			if value != "" {
+				result.Nationality = value
+			}
+		}
+
+		for _, v := range votes[attr] {
+			if !v.present {
+				continue
+			}
+			source := models.EnrichmentSource{
+				Provider:    v.provider,
+				Attribute:   attr,
+				Value:       v.value,
+				Probability: v.probability,
+				Weight:      v.weight,
+				Selected:    v.selected,
+			}
+			if v.err != nil {
+				source.Error = v.err.Error()
+			}
+			result.Sources = append(result.Sources, source)
+		}
+	}
+	return result, nil
+}
+
+func estimator(p interface{ Name() string }, attr string) func(Query) (string, float64, error) {
+	switch attr {
+	case attrGender:
+		if gp, ok := p.(GenderProvider); ok {
+			return gp.EstimateGender
+		}
+	case attrAge:
+		if ap, ok := p.(AgeProvider); ok {
+			return func(q Query) (string, float64, error) {
+				age, probability, err := ap.EstimateAge(q)
+				if err != nil {
+					return "", 0, err
+				}
+				return strconv.Itoa(age), probability, nil
+			}
+		}
+	case attrNationality:
+		if np, ok := p.(NationalityProvider); ok {
+			return np.EstimateNationality
+		}
+	}
+	return nil
+}
+
+// choose выбирает итоговое значение и помечает учтенные голоса.
+// Ошибка возвращается, только если ни один провайдер не ответил и хотя бы один упал.
+func (c *Composite) choose(attr string, votes []vote) (string, error) {
+	var ok []int
+	var lastErr error
+	for i, v := range votes {
+		if !v.present {
+			continue
+		}
+		if v.err == nil {
+			ok = append(ok, i)
+		} else if !errors.Is(v.err, ErrNoEstimate) {
+			lastErr = v.err
+		}
+	}
+	if len(ok) == 0 {
+		return "", lastErr
+	}
+
+	if c.strategy == StrategyWeighted && attr == attrAge {
+		if value, done := weightedAge(votes, ok); done {
+			return value, nil
+		}
+	}
+
+	var winner string
+	switch c.strategy {
+	case StrategyMajority, StrategyWeighted:
+		counts := map[string]int{}
+		scores := map[string]float64{}
+		for _, i := range ok {
+			counts[votes[i].value]++
+			scores[votes[i].value] += votes[i].score()
+		}
+
+		winner = votes[ok[0]].value
+		for _, i := range ok {
+			value := votes[i].value
+			if c.strategy == StrategyMajority && counts[value] != counts[winner] {
+				if counts[value] > counts[winner] {
+					winner = value
+				}
+				continue
+			}
+			if scores[value] > scores[winner] {
+				winner = value
+			}
+		}
+	default:
+		votes[ok[0]].selected = true
+		return votes[ok[0]].value, nil
+	}
+
+	for _, i := range ok {
+		if votes[i].value == winner {
+			votes[i].selected = true
+		}
+	}
+	return winner, nil
+}
+
+func weightedAge(votes []vote, ok []int) (string, bool) {
+	var sum, total float64
+	for _, i := range ok {
+		age, err := strconv.ParseFloat(votes[i].value, 64)
+		if err != nil {
+			continue
+		}
+		sum += age * votes[i].score()
+		total += votes[i].score()
+	}
+	if total == 0 {
+		return "", false
+	}
+
+	for _, i := range ok {
+		if votes[i].score() > 0 {
+			votes[i].selected = true
+		}
+	}
+	return strconv.Itoa(int(math.Round(sum / total))), true
+}
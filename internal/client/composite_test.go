@@ -0,0 +1,222 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+type estimate struct {
+	value       string
+	age         int
+	probability float64
+	err         error
+}
+
+type fakeProvider struct {
+	name        string
+	gender      *estimate
+	age         *estimate
+	nationality *estimate
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) EstimateGender(Query) (string, float64, error) {
+	if p.gender == nil {
+		return "", 0, ErrNoEstimate
+	}
+	return p.gender.value, p.gender.probability, p.gender.err
+}
+
+func (p *fakeProvider) EstimateAge(Query) (int, float64, error) {
+	if p.age == nil {
+		return 0, 0, ErrNoEstimate
+	}
+	return p.age.age, p.age.probability, p.age.err
+}
+
+func (p *fakeProvider) EstimateNationality(Query) (string, float64, error) {
+	if p.nationality == nil {
+		return "", 0, ErrNoEstimate
+	}
+	return p.nationality.value, p.nationality.probability, p.nationality.err
+}
+
+func TestCompositeStrategies(t *testing.T) {
+	a := &fakeProvider{
+		name:        "a",
+		gender:      &estimate{value: "male", probability: 0.6},
+		age:         &estimate{age: 20, probability: 1},
+		nationality: &estimate{value: "RU", probability: 0.5},
+	}
+	b := &fakeProvider{
+		name:        "b",
+		gender:      &estimate{value: "female", probability: 0.9},
+		age:         &estimate{age: 40, probability: 1},
+		nationality: &estimate{value: "UA", probability: 0.9},
+	}
+	c := &fakeProvider{
+		name:   "c",
+		gender: &estimate{value: "female", probability: 0.55},
+		age:    &estimate{age: 60, probability: 0.5},
+	}
+
+	tests := []struct {
+		name            string
+		strategy        Strategy
+		sources         []Source
+		wantGender      string
+		wantAge         int
+		wantNationality string
+	}{
+		{
+			name:            "first success keeps registration order",
+			strategy:        StrategyFirstSuccess,
+			sources:         []Source{{a, 1}, {b, 1}, {c, 1}},
+			wantGender:      "male",
+			wantAge:         20,
+			wantNationality: "RU",
+		},
+		{
+			name:            "first success skips providers without estimate",
+			strategy:        StrategyFirstSuccess,
+			sources:         []Source{{c, 1}, {a, 1}},
+			wantGender:      "female",
+			wantAge:         60,
+			wantNationality: "RU",
+		},
+		{
+			name:            "majority counts votes",
+			strategy:        StrategyMajority,
+			sources:         []Source{{a, 1}, {b, 1}, {c, 1}},
+			wantGender:      "female",
+			wantAge:         20,
+			wantNationality: "UA",
+		},
+		{
+			name:            "majority tie broken by score",
+			strategy:        StrategyMajority,
+			sources:         []Source{{a, 1}, {b, 1}},
+			wantGender:      "female",
+			wantAge:         20,
+			wantNationality: "UA",
+		},
+		{
+			name:            "weighted prefers heavier source",
+			strategy:        StrategyWeighted,
+			sources:         []Source{{a, 10}, {b, 1}},
+			wantGender:      "male",
+			wantAge:         22,
+			wantNationality: "RU",
+		},
+		{
+			name:            "weighted averages age by score",
+			strategy:        StrategyWeighted,
+			sources:         []Source{{a, 1}, {b, 1}, {c, 1}},
+			wantGender:      "female",
+			wantAge:         36,
+			wantNationality: "UA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewComposite(tt.strategy, tt.sources...).Enrich(Query{Name: "Test"})
+			if err != nil {
+				t.Fatalf("Enrich: %v", err)
+			}
+			if got.Gender != tt.wantGender || got.Age != tt.wantAge || got.Nationality != tt.wantNationality {
+				t.Errorf("got %s/%d/%s, want %s/%d/%s",
+					got.Gender, got.Age, got.Nationality, tt.wantGender, tt.wantAge, tt.wantNationality)
+			}
+		})
+	}
+}
+
+func TestCompositeMarksSelectedSources(t *testing.T) {
+	a := &fakeProvider{name: "a", gender: &estimate{value: "male", probability: 0.9}}
+	b := &fakeProvider{name: "b", gender: &estimate{value: "female", probability: 0.5}}
+
+	got, err := NewComposite(StrategyWeighted, Source{a, 1}, Source{b, 1}).Enrich(Query{Name: "Test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := map[string]bool{}
+	for _, s := range got.Sources {
+		if s.Attribute == attrGender {
+			selected[s.Provider] = s.Selected
+		}
+	}
+	if !selected["a"] || selected["b"] {
+		t.Errorf("selected = %v, want only a", selected)
+	}
+}
+
+func TestCompositeErrors(t *testing.T) {
+	errDown := errors.New("provider down")
+
+	tests := []struct {
+		name    string
+		sources []Source
+		wantErr bool
+	}{
+		{
+			name:    "all providers failed",
+			sources: []Source{{&fakeProvider{name: "a", gender: &estimate{err: errDown}}, 1}},
+			wantErr: true,
+		},
+		{
+			name: "one provider answered",
+			sources: []Source{
+				{&fakeProvider{name: "a", gender: &estimate{err: errDown}}, 1},
+				{&fakeProvider{name: "b", gender: &estimate{value: "male", probability: 1}}, 1},
+			},
+		},
+		{
+			name:    "no estimate is not an error",
+			sources: []Source{{&fakeProvider{name: "a"}, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewComposite(StrategyWeighted, tt.sources...).Enrich(Query{Name: "Test"})
+			if tt.wantErr {
+				if !errors.Is(err, errDown) {
+					t.Fatalf("err = %v, want %v", err, errDown)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Enrich: %v", err)
+			}
+			if got.Nationality != "unknown" {
+				t.Errorf("Nationality = %q, want unknown", got.Nationality)
+			}
+		})
+	}
+}
+
+func TestPatronymicProvider(t *testing.T) {
+	tests := []struct {
+		patronymic string
+		want       string
+		wantErr    error
+	}{
+		{"Иванович", "male", nil},
+		{"Ивановна", "female", nil},
+		{"Ильинична", "female", nil},
+		{"Ibragim ogly", "male", nil},
+		{"Petrovna", "female", nil},
+		{"", "", ErrNoEstimate},
+		{"Smith", "", ErrNoEstimate},
+	}
+
+	p := NewPatronymicProvider()
+	for _, tt := range tests {
+		got, _, err := p.EstimateGender(Query{Patronymic: tt.patronymic})
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("EstimateGender(%q) = %q, %v; want %q, %v", tt.patronymic, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
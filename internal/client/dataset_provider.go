@@ -0,0 +1,88 @@
+package client
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type datasetRecord struct {
+	gender      string
+	age         int
+	nationality string
+}
+
+// DatasetProvider отвечает по офлайн-справочнику имен в формате CSV:
+// name,gender,age,nationality (пустые значения допускаются).
+type DatasetProvider struct {
+	records map[string]datasetRecord
+}
+
+func LoadDatasetProvider(path string) (*DatasetProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewDatasetProvider(f)
+}
+
+func NewDatasetProvider(r io.Reader) (*DatasetProvider, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	p := &DatasetProvider{records: make(map[string]datasetRecord, len(rows))}
+	for i, row := range rows {
+		if i == 0 && strings.EqualFold(row[0], "name") {
+			continue
+		}
+
+		rec := datasetRecord{gender: row[1], nationality: row[3]}
+		if row[2] != "" {
+			rec.age, err = strconv.Atoi(row[2])
+			if err != nil {
+				return nil, fmt.Errorf("dataset line %d: invalid age %q", i+1, row[2])
+			}
+		}
+		p.records[strings.ToLower(row[0])] = rec
+	}
+	return p, nil
+}
+
+func (p *DatasetProvider) Name() string {
+	return "dataset"
+}
+
+func (p *DatasetProvider) EstimateAge(q Query) (int, float64, error) {
+	rec, ok := p.records[strings.ToLower(q.Name)]
+	if !ok || rec.age == 0 {
+		return 0, 0, ErrNoEstimate
+	}
+	return rec.age, 1, nil
+}
+
+func (p *DatasetProvider) EstimateGender(q Query) (string, float64, error) {
+	rec, ok := p.records[strings.ToLower(q.Name)]
+	if !ok || rec.gender == "" {
+		return "", 0, ErrNoEstimate
+	}
+	return rec.gender, 1, nil
+}
+
+func (p *DatasetProvider) EstimateNationality(q Query) (string, float64, error) {
+	rec, ok := p.records[strings.ToLower(q.Name)]
+	if !ok || rec.nationality == "" {
+		return "", 0, ErrNoEstimate
+	}
+	return rec.nationality, 1, nil
+}
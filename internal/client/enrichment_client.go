@@ -3,13 +3,29 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	providerGenderize   = "genderize"
+	providerAgify       = "agify"
+	providerNationalize = "nationalize"
 )
 
 type EnrichmentClient struct {
 	GenderizeURL   string
 	AgifyURL       string
 	NationalizeURL string
+	// CountryHint уточняет прогноз genderize/agify для конкретной страны (ISO 3166-1 alpha-2).
+	CountryHint string
+	Cache       *Cache
+
+	group singleflight.Group
+	stats map[string]*providerStats
 }
 
 func NewEnrichmentClient(genderize, agify, nationalize string) *EnrichmentClient {
@@ -17,58 +33,116 @@ func NewEnrichmentClient(genderize, agify, nationalize string) *EnrichmentClient
 		GenderizeURL:   genderize,
 		AgifyURL:       agify,
 		NationalizeURL: nationalize,
+		stats: map[string]*providerStats{
+			providerGenderize:   {},
+			providerAgify:       {},
+			providerNationalize: {},
+		},
 	}
 }
 
-func (c *EnrichmentClient) GetAge(name string) (int, error) {
-	resp, err := http.Get(fmt.Sprintf("%s?name=%s", c.AgifyURL, name))
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
+func (c *EnrichmentClient) Name() string {
+	return "http"
+}
 
+func (c *EnrichmentClient) EstimateAge(q Query) (int, float64, error) {
 	var data struct {
-		Age int `json:"age"`
+		Age   *int `json:"age"`
+		Count int  `json:"count"`
+	}
+	if err := c.lookup(providerAgify, c.AgifyURL, q.Name, c.CountryHint, &data); err != nil {
+		return 0, 0, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, err
+	if data.Age == nil {
+		return 0, 0, ErrNoEstimate
 	}
-	return data.Age, nil
+	// agify не отдает вероятность, поэтому уверенность растет с размером выборки
+	return *data.Age, float64(data.Count) / float64(data.Count+10), nil
 }
 
-func (c *EnrichmentClient) GetGender(name string) (string, error) {
-	resp, err := http.Get(fmt.Sprintf("%s?name=%s", c.GenderizeURL, name))
-	if err != nil {
-		return "", err
+func (c *EnrichmentClient) EstimateGender(q Query) (string, float64, error) {
+	var data struct {
+		Gender      string  `json:"gender"`
+		Probability float64 `json:"probability"`
 	}
-	defer resp.Body.Close()
+	if err := c.lookup(providerGenderize, c.GenderizeURL, q.Name, c.CountryHint, &data); err != nil {
+		return "", 0, err
+	}
+	if data.Gender == "" {
+		return "", 0, ErrNoEstimate
+	}
+	return data.Gender, data.Probability, nil
+}
 
+func (c *EnrichmentClient) EstimateNationality(q Query) (string, float64, error) {
 	var data struct {
-		Gender string `json:"gender"`
+		Country []struct {
+			CountryID   string  `json:"country_id"`
+			Probability float64 `json:"probability"`
+		} `json:"country"`
+	}
+	if err := c.lookup(providerNationalize, c.NationalizeURL, q.Name, "", &data); err != nil {
+		return "", 0, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", err
+	if len(data.Country) == 0 {
+		return "", 0, ErrNoEstimate
 	}
-	return data.Gender, nil
+	return data.Country[0].CountryID, data.Country[0].Probability, nil
 }
 
-func (c *EnrichmentClient) GetNationality(name string) (string, error) {
-	resp, err := http.Get(fmt.Sprintf("%s?name=%s", c.NationalizeURL, name))
+func (c *EnrichmentClient) lookup(provider, baseURL, name, country string, out interface{}) error {
+	key := provider + "|" + name + "|" + country
+	stats := c.stats[provider]
+	load := func() ([]byte, error) {
+		stats.upstream.Add(1)
+		return c.fetch(baseURL, name, country)
+	}
+
+	// одинаковые запросы объединяет кэш, а без него - собственная группа клиента
+	var body []byte
+	var coalesced bool
+	var err error
+	if c.Cache != nil {
+		body, coalesced, err = c.Cache.Get(key, load)
+	} else {
+		body, coalesced, err = c.coalesce(key, load)
+	}
+	if coalesced {
+		stats.coalesced.Add(1)
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer resp.Body.Close()
+	return json.Unmarshal(body, out)
+}
 
-	var data struct {
-		Country []struct {
-			CountryID string `json:"country_id"`
-		} `json:"country"`
+// coalesce объединяет одновременные одинаковые запросы к провайдеру в один.
+func (c *EnrichmentClient) coalesce(key string, load func() ([]byte, error)) ([]byte, bool, error) {
+	loaded := false
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		loaded = true
+		return load()
+	})
+	if err != nil {
+		return nil, !loaded, err
+	}
+	return v.([]byte), !loaded, nil
+}
+
+func (c *EnrichmentClient) fetch(baseURL, name, country string) ([]byte, error) {
+	query := url.Values{"name": {name}}
+	if country != "" {
+		query.Set("country_id", country)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", err
+
+	resp, err := http.Get(fmt.Sprintf("%s?%s", baseURL, query.Encode()))
+	if err != nil {
+		return nil, err
 	}
-	if len(data.Country) > 0 {
-		return data.Country[0].CountryID, nil
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %d", baseURL, resp.StatusCode)
 	}
-	return "unknown", nil
+	return io.ReadAll(resp.Body)
 }
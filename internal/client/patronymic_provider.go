@@ -0,0 +1,50 @@
+package client
+
+import "strings"
+
+type patronymicRule struct {
+	suffix      string
+	gender      string
+	probability float64
+}
+
+var patronymicRules = []patronymicRule{
+	{"вич", "male", 0.99},
+	{"vich", "male", 0.99},
+	{"ич", "male", 0.9},
+	{"ich", "male", 0.9},
+	{"оглы", "male", 0.95},
+	{"ogly", "male", 0.95},
+	{"вна", "female", 0.99},
+	{"vna", "female", 0.99},
+	{"чна", "female", 0.95},
+	{"chna", "female", 0.95},
+	{"кызы", "female", 0.95},
+	{"kyzy", "female", 0.95},
+	{"qizi", "female", 0.95},
+}
+
+// PatronymicProvider определяет пол по окончанию отчества.
+type PatronymicProvider struct{}
+
+func NewPatronymicProvider() *PatronymicProvider {
+	return &PatronymicProvider{}
+}
+
+func (p *PatronymicProvider) Name() string {
+	return "patronymic"
+}
+
+func (p *PatronymicProvider) EstimateGender(q Query) (string, float64, error) {
+	patronymic := strings.ToLower(strings.TrimSpace(q.Patronymic))
+	if patronymic == "" {
+		return "", 0, ErrNoEstimate
+	}
+
+	for _, rule := range patronymicRules {
+		if strings.HasSuffix(patronymic, rule.suffix) {
+			return rule.gender, rule.probability, nil
+		}
+	}
+	return "", 0, ErrNoEstimate
+}
@@ -0,0 +1,29 @@
+package client
+
+import "errors"
+
+// ErrNoEstimate возвращается провайдером, у которого нет данных по запросу.
+var ErrNoEstimate = errors.New("no estimate")
+
+type Query struct {
+	Name       string
+	Surname    string
+	Patronymic string
+}
+
+// Провайдеры возвращают значение и уверенность в нем от 0 до 1.
+
+type GenderProvider interface {
+	Name() string
+	EstimateGender(q Query) (string, float64, error)
+}
+
+type AgeProvider interface {
+	Name() string
+	EstimateAge(q Query) (int, float64, error)
+}
+
+type NationalityProvider interface {
+	Name() string
+	EstimateNationality(q Query) (string, float64, error)
+}
@@ -0,0 +1,36 @@
+package client
+
+import "sync/atomic"
+
+type providerStats struct {
+	upstream  atomic.Int64
+	coalesced atomic.Int64
+}
+
+// ProviderStats показывает, сколько обращений к провайдеру было объединено в общие запросы.
+// Ответы из кэша не учитываются: Requests - это обращения, которым понадобился ответ провайдера.
+type ProviderStats struct {
+	Requests        int64   `json:"requests"`
+	Upstream        int64   `json:"upstream"`
+	Coalesced       int64   `json:"coalesced"`
+	CoalescingRatio float64 `json:"coalescing_ratio"`
+}
+
+func (c *EnrichmentClient) Stats() map[string]ProviderStats {
+	result := make(map[string]ProviderStats, len(c.stats))
+	for provider, s := range c.stats {
+		upstream := s.upstream.Load()
+		coalesced := s.coalesced.Load()
+
+		ps := ProviderStats{
+			Requests:  upstream + coalesced,
+			Upstream:  upstream,
+			Coalesced: coalesced,
+		}
+		if ps.Requests > 0 {
+			ps.CoalescingRatio = float64(coalesced) / float64(ps.Requests)
+		}
+		result[provider] = ps
+	}
+	return result
+}
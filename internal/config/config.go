@@ -3,11 +3,14 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	Storage           string
 	DBHost            string
 	DBPort            string
 	DBUser            string
@@ -16,11 +19,43 @@ type Config struct {
 	DBSSLMode         string
 	ServerHost        string
 	ServerPort        string
+	AdminAddr         string
 	LogLevel          string
 	LogFormat         string
+	RequireIfMatch    bool
+	AdminToken        string
+	PurgeInterval     time.Duration
+	PurgeRetention    time.Duration
+	ImportInterval    time.Duration
+	ImportLease       time.Duration
+	IdempotencyTTL    time.Duration
 	GenderizeAPIURL   string
 	AgifyAPIURL       string
 	NationalizeAPIURL string
+
+	ServerWriteTimeout time.Duration
+	ExportWriteTimeout time.Duration
+
+	IdempotencyLockTimeout     time.Duration
+	IdempotencyCleanupInterval time.Duration
+
+	EnrichmentCountryHint  string
+	EnrichmentCacheSoftTTL time.Duration
+	EnrichmentCacheHardTTL time.Duration
+	EnrichmentCacheSize    int
+
+	EnrichmentStrategy         string
+	EnrichmentHTTPWeight       float64
+	EnrichmentDatasetPath      string
+	EnrichmentDatasetWeight    float64
+	EnrichmentPatronymicWeight float64
+
+	DuplicateDetection string
+	DuplicateThreshold float64
+	UniquePersonNames  bool
+
+	TxIsolation  string
+	TxMaxRetries int
 }
 
 func LoadConfig() *Config {
@@ -30,6 +65,7 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
+		Storage:           getEnv("STORAGE", "postgres"),
 		DBHost:            getEnv("DB_HOST", "localhost"),
 		DBPort:            getEnv("DB_PORT", "5432"),
 		DBUser:            getEnv("DB_USER", "postgres"),
@@ -38,11 +74,43 @@ func LoadConfig() *Config {
 		DBSSLMode:         getEnv("DB_SSLMODE", "disable"),
 		ServerHost:        getEnv("SERVER_HOST", "0.0.0.0"),
 		ServerPort:        getEnv("SERVER_PORT", "8080"),
+		AdminAddr:         getEnv("ADMIN_ADDR", "127.0.0.1:8081"),
 		LogLevel:          getEnv("LOG_LEVEL", "info"),
 		LogFormat:         getEnv("LOG_FORMAT", "text"),
+		RequireIfMatch:    getBoolEnv("REQUIRE_IF_MATCH", false),
+		AdminToken:        getEnv("ADMIN_TOKEN", ""),
+		PurgeInterval:     getDurationEnv("PURGE_INTERVAL", time.Hour),
+		PurgeRetention:    getDurationEnv("PURGE_RETENTION", 30*24*time.Hour),
+		ImportInterval:    getDurationEnv("IMPORT_INTERVAL", 2*time.Second),
+		ImportLease:       getDurationEnv("IMPORT_LEASE", time.Minute),
+		IdempotencyTTL:    getDurationEnv("IDEMPOTENCY_TTL", 24*time.Hour),
 		GenderizeAPIURL:   getEnv("GENDERIZE_API_URL", "https://api.genderize.io"),
 		AgifyAPIURL:       getEnv("AGIFY_API_URL", "https://api.agify.io"),
 		NationalizeAPIURL: getEnv("NATIONALIZE_API_URL", "https://api.nationalize.io"),
+
+		ServerWriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
+		ExportWriteTimeout: getDurationEnv("EXPORT_WRITE_TIMEOUT", 30*time.Second),
+
+		IdempotencyLockTimeout:     getDurationEnv("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute),
+		IdempotencyCleanupInterval: getDurationEnv("IDEMPOTENCY_CLEANUP_INTERVAL", time.Hour),
+
+		EnrichmentCountryHint:  getEnv("ENRICHMENT_COUNTRY_HINT", ""),
+		EnrichmentCacheSoftTTL: getDurationEnv("ENRICHMENT_CACHE_SOFT_TTL", 24*time.Hour),
+		EnrichmentCacheHardTTL: getDurationEnv("ENRICHMENT_CACHE_HARD_TTL", 30*24*time.Hour),
+		EnrichmentCacheSize:    getIntEnv("ENRICHMENT_CACHE_SIZE", 10000),
+
+		EnrichmentStrategy:         getEnv("ENRICHMENT_STRATEGY", "weighted"),
+		EnrichmentHTTPWeight:       getFloatEnv("ENRICHMENT_HTTP_WEIGHT", 1),
+		EnrichmentDatasetPath:      getEnv("ENRICHMENT_DATASET_PATH", ""),
+		EnrichmentDatasetWeight:    getFloatEnv("ENRICHMENT_DATASET_WEIGHT", 1),
+		EnrichmentPatronymicWeight: getFloatEnv("ENRICHMENT_PATRONYMIC_WEIGHT", 1),
+
+		DuplicateDetection: getEnv("DUPLICATE_DETECTION", "exact"),
+		DuplicateThreshold: getFloatEnv("DUPLICATE_THRESHOLD", 0.6),
+		UniquePersonNames:  getBoolEnv("UNIQUE_PERSON_NAMES", false),
+
+		TxIsolation:  getEnv("TX_ISOLATION", "read_committed"),
+		TxMaxRetries: getIntEnv("TX_MAX_RETRIES", 3),
 	}
 }
 
@@ -52,3 +120,55 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+func getDurationEnv(key string, defaultVal time.Duration) time.Duration {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Invalid duration in %s=%q, using default %s", key, val, defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
+func getFloatEnv(key string, defaultVal float64) float64 {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Printf("Invalid number in %s=%q, using default %v", key, val, defaultVal)
+		return defaultVal
+	}
+	return f
+}
+
+func getIntEnv(key string, defaultVal int) int {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("Invalid integer in %s=%q, using default %v", key, val, defaultVal)
+		return defaultVal
+	}
+	return n
+}
+
+func getBoolEnv(key string, defaultVal bool) bool {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("Invalid boolean in %s=%q, using default %v", key, val, defaultVal)
+		return defaultVal
+	}
+	return b
+}
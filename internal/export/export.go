@@ -0,0 +1,225 @@
+// Package export пишет выгрузку людей в CSV, NDJSON или XLSX построчно, не накапливая ее в памяти.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"effective-mobile-task/internal/models"
+)
+
+// ErrInvalidColumns - запрошен неизвестный или повторенный столбец.
+var ErrInvalidColumns = errors.New("invalid columns")
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// ContentTypes - тип содержимого каждого формата.
+var ContentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Column - столбец выгрузки. Значение - string, int, time.Time или nil, если его нет.
+type Column struct {
+	Name  string
+	value func(p *models.Person) interface{}
+}
+
+var columns = []Column{
+	{"id", func(p *models.Person) interface{} { return p.ID.String() }},
+	{"name", func(p *models.Person) interface{} { return p.Name }},
+	{"surname", func(p *models.Person) interface{} { return p.Surname }},
+	{"patronymic", func(p *models.Person) interface{} { return p.Patronymic }},
+	{"name_original", func(p *models.Person) interface{} { return p.NameOriginal }},
+	{"surname_original", func(p *models.Person) interface{} { return p.SurnameOriginal }},
+	{"patronymic_original", func(p *models.Person) interface{} { return p.PatronymicOriginal }},
+	{"age", func(p *models.Person) interface{} { return p.Age }},
+	{"birth_year", func(p *models.Person) interface{} { return optionalInt(p.BirthYear) }},
+	{"gender", func(p *models.Person) interface{} { return p.Gender }},
+	{"nationality", func(p *models.Person) interface{} { return p.Nationality }},
+	{"created_at", func(p *models.Person) interface{} { return p.CreatedAt }},
+	{"updated_at", func(p *models.Person) interface{} { return p.UpdatedAt }},
+	{"version", func(p *models.Person) interface{} { return p.Version }},
+	{"deleted_at", func(p *models.Person) interface{} {
+		if p.DeletedAt == nil {
+			return nil
+		}
+		return *p.DeletedAt
+	}},
+}
+
+// DefaultColumns - столбцы выгрузки, если набор не указан.
+var DefaultColumns = []string{"id", "name", "surname", "patronymic", "age", "gender", "nationality", "created_at", "updated_at"}
+
+// ParseColumns разбирает список столбцов через запятую; пустая строка - DefaultColumns.
+func ParseColumns(s string) ([]Column, error) {
+	names := DefaultColumns
+	if s != "" {
+		names = strings.Split(s, ",")
+	}
+
+	var result []Column
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(columns, func(c Column) bool { return c.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidColumns, name)
+		}
+		if slices.ContainsFunc(result, func(c Column) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidColumns, name)
+		}
+		result = append(result, columns[i])
+	}
+	return result, nil
+}
+
+// Writer пишет людей в выбранном формате. Заголовок пишется вместе с первой строкой
+// или в Close, поэтому до первого Write в w ничего не попадает.
+type Writer struct {
+	columns []Column
+	enc     encoder
+	started bool
+}
+
+type encoder interface {
+	header(columns []Column) error
+	row(columns []Column, values []interface{}) error
+	close() error
+}
+
+// NewWriter создает Writer для format из ContentTypes.
+func NewWriter(w io.Writer, format string, columns []Column) (*Writer, error) {
+	var enc encoder
+	switch format {
+	case FormatCSV:
+		enc = &csvEncoder{w: csv.NewWriter(w)}
+	case FormatNDJSON:
+		enc = &ndjsonEncoder{w: bufio.NewWriter(w)}
+	case FormatXLSX:
+		enc = newXLSXEncoder(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+	return &Writer{columns: columns, enc: enc}, nil
+}
+
+// Started сообщает, начата ли запись в w.
+func (w *Writer) Started() bool {
+	return w.started
+}
+
+func (w *Writer) Write(p models.Person) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(w.columns))
+	for i, c := range w.columns {
+		values[i] = c.value(&p)
+	}
+	return w.enc.row(w.columns, values)
+}
+
+// Close дописывает выгрузку; без строк получается файл из одного заголовка.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.enc.close()
+}
+
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.enc.header(w.columns)
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) header(columns []Column) error {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+	return e.w.Write(names)
+}
+
+func (e *csvEncoder) row(_ []Column, values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *ndjsonEncoder) header([]Column) error {
+	return nil
+}
+
+// row пишет объект вручную, чтобы ключи шли в порядке столбцов.
+func (e *ndjsonEncoder) row(columns []Column, values []interface{}) error {
+	e.w.WriteByte('{')
+	for i, c := range columns {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(c.Name)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		e.w.Write(key)
+		e.w.WriteByte(':')
+		e.w.Write(value)
+	}
+	_, err := e.w.WriteString("}\n")
+	return err
+}
+
+func (e *ndjsonEncoder) close() error {
+	return e.w.Flush()
+}
+
+func optionalInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+)
+
+// xlsxSheet - лист в том виде, в каком его пишет xlsxEncoder.
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX проверяет состав архива и возвращает значения ячеек листа; у числовых ячеек
+// значение помечено префиксом "#", у пустых - "<nil>".
+func readXLSX(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+
+	var names []string
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		files[f.Name] = body
+	}
+	want := []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("archive parts = %v, want %v", names, want)
+	}
+	for name, body := range files {
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", name, err)
+			}
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]string
+	for _, r := range sheet.Rows {
+		var row []string
+		for _, c := range r.Cells {
+			switch {
+			case c.Type == "inlineStr":
+				row = append(row, c.Inline)
+			case c.Value != "":
+				row = append(row, "#"+c.Value)
+			default:
+				row = append(row, "<nil>")
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestXLSXWriter(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	deleted := created.Add(time.Hour)
+	id := uuid.MustParse("1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9")
+
+	tests := []struct {
+		name    string
+		columns string
+		people  []models.Person
+		want    [][]string
+	}{
+		{
+			name:    "empty export has only the header",
+			columns: "id,name",
+			want:    [][]string{{"id", "name"}},
+		},
+		{
+			name:    "strings, numbers and times",
+			columns: "id,surname,age,created_at",
+			people:  []models.Person{{ID: id, Surname: "Иванов", Age: 30, CreatedAt: created}},
+			want: [][]string{
+				{"id", "surname", "age", "created_at"},
+				{id.String(), "Иванов", "#30", "2025-03-01T12:30:00Z"},
+			},
+		},
+		{
+			name:    "missing values are empty cells",
+			columns: "birth_year,deleted_at,patronymic",
+			people: []models.Person{
+				{},
+				{BirthYear: 1995, DeletedAt: &deleted},
+			},
+			want: [][]string{
+				{"birth_year", "deleted_at", "patronymic"},
+				{"<nil>", "<nil>", ""},
+				{"#1995", "2025-03-01T13:30:00Z", ""},
+			},
+		},
+		{
+			name:    "markup and whitespace are kept as text",
+			columns: "name,surname",
+			people:  []models.Person{{Name: `<b>&"Анна"</b>`, Surname: "  Д'Артаньян  "}},
+			want: [][]string{
+				{"name", "surname"},
+				{`<b>&"Анна"</b>`, "  Д'Артаньян  "},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := ParseColumns(tt.columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, FormatXLSX, columns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tt.people {
+				if err := w.Write(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := readXLSX(t, buf.Bytes())
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLSXWriterWritesNothingBeforeFirstRow(t *testing.T) {
+	columns, _ := ParseColumns("")
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatXLSX, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Started() || buf.Len() != 0 {
+		t.Fatalf("writer started before the first row: %d bytes written", buf.Len())
+	}
+	if err := w.Write(models.Person{Name: "Иван"}); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Started() {
+		t.Error("Started() = false after Write")
+	}
+}
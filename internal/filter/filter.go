@@ -0,0 +1,295 @@
+// Package filter разбирает фильтры списка людей из query-параметров в типизированное дерево условий.
+//
+// Значение параметра имеет вид [not:][op:]values, где values перечисляются через запятую:
+//
+//	gender=male,female
+//	nationality=in:RU,UA
+//	nationality=not:in:RU,UA
+//	age=between:20,30
+//	created_at=gte:2024-01-01
+//	created_at=2024-01-01
+//	patronymic=null:true
+//	surname=prefix:Ив
+//	name=exact:Иван
+//
+// Несколько значений для eq, prefix и contains означают "любое из". Повторенный параметр
+// (age=gte:20&age=lte:30) добавляет еще одно условие, все условия объединяются через AND.
+//
+// Дата без времени означает целые сутки по UTC: eq разворачивается в OpRange [день, день+1),
+// gt и lte сравнивают с началом следующего дня, верхняя граница between включает весь день.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFilter - фильтр с неизвестным оператором или значением неверного типа.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpIn       Op = "in"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpBetween  Op = "between"
+	OpNull     Op = "null"
+	OpPrefix   Op = "prefix"
+	OpContains Op = "contains"
+	// OpRange - полуинтервал [Values[0], Values[1]). В запросе не задается:
+	// в него разворачивается сравнение с датой без времени.
+	OpRange Op = "range"
+)
+
+type Kind int
+
+const (
+	// KindText - строки, сравниваются без учета регистра.
+	KindText Kind = iota
+	// KindEnum - строки из небольшого набора значений, сравниваются точно.
+	KindEnum
+	// KindInt - целые числа.
+	KindInt
+	// KindTime - моменты времени в RFC 3339 или даты ГГГГ-ММ-ДД.
+	KindTime
+)
+
+type field struct {
+	kind      Kind
+	defaultOp Op
+	nullable  bool
+}
+
+var fields = map[string]field{
+	"name":        {kind: KindText, defaultOp: OpContains},
+	"surname":     {kind: KindText, defaultOp: OpContains},
+	"patronymic":  {kind: KindText, defaultOp: OpContains, nullable: true},
+	"gender":      {kind: KindEnum, defaultOp: OpEq},
+	"nationality": {kind: KindEnum, defaultOp: OpEq},
+	"age":         {kind: KindInt, defaultOp: OpEq},
+	"created_at":  {kind: KindTime, defaultOp: OpEq},
+	"updated_at":  {kind: KindTime, defaultOp: OpEq},
+}
+
+// legacyParams - прежние параметры фильтрации по возрасту с фиксированным оператором.
+var legacyParams = map[string]struct {
+	field string
+	op    Op
+}{
+	"age_gt": {"age", OpGt},
+	"age_lt": {"age", OpLt},
+}
+
+var kindOps = map[Kind][]Op{
+	KindText: {OpEq, OpIn, OpPrefix, OpContains, OpNull},
+	KindEnum: {OpEq, OpIn},
+	KindInt:  {OpEq, OpIn, OpGt, OpGte, OpLt, OpLte, OpBetween},
+	KindTime: {OpEq, OpGt, OpGte, OpLt, OpLte, OpBetween},
+}
+
+// Condition - одно условие фильтра. Values содержит string, int или time.Time
+// в зависимости от Kind поля; для OpNull - одно значение bool.
+type Condition struct {
+	Field  string
+	Op     Op
+	Not    bool
+	Values []interface{}
+}
+
+type Filter struct {
+	Conditions     []Condition
+	IncludeDeleted bool
+}
+
+// KindOf возвращает тип поля, по которому можно фильтровать.
+func KindOf(name string) Kind {
+	return fields[name].kind
+}
+
+// Parse строит фильтр из query-параметров, пропуская параметры, не относящиеся к фильтрации.
+func Parse(q url.Values) (Filter, error) {
+	var f Filter
+	if v := q.Get("include_deleted"); v != "" {
+		includeDeleted, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("%w: include_deleted must be a boolean", ErrInvalidFilter)
+		}
+		f.IncludeDeleted = includeDeleted
+	}
+
+	for _, param := range slices.Sorted(maps.Keys(q)) {
+		raws := q[param]
+		name, forcedOp := param, Op("")
+		if legacy, ok := legacyParams[param]; ok {
+			name, forcedOp = legacy.field, legacy.op
+		}
+		if _, ok := fields[name]; !ok {
+			continue
+		}
+
+		for _, raw := range raws {
+			if raw == "" {
+				continue
+			}
+			c, err := parseCondition(name, forcedOp, raw)
+			if err != nil {
+				return f, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, param, err)
+			}
+			f.Conditions = append(f.Conditions, c)
+		}
+	}
+	return f, nil
+}
+
+func parseCondition(name string, forcedOp Op, raw string) (Condition, error) {
+	spec := fields[name]
+	c := Condition{Field: name, Op: forcedOp}
+
+	if rest, ok := strings.CutPrefix(raw, "not:"); ok {
+		c.Not, raw = true, rest
+	}
+	if c.Op == "" {
+		c.Op = spec.defaultOp
+		if op, rest, ok := strings.Cut(raw, ":"); ok && isOp(Op(op)) {
+			c.Op, raw = Op(op), rest
+		} else if rest, ok := strings.CutPrefix(raw, "exact:"); ok {
+			c.Op, raw = OpEq, rest
+		}
+	}
+	if !allowed(spec, c.Op) {
+		return c, fmt.Errorf("operator %q is not supported", c.Op)
+	}
+
+	if c.Op == OpNull {
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return c, fmt.Errorf("null expects true or false")
+		}
+		c.Values = []interface{}{isNull}
+		return c, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	switch c.Op {
+	case OpGt, OpGte, OpLt, OpLte:
+		if len(parts) != 1 {
+			return c, fmt.Errorf("%s expects a single value", c.Op)
+		}
+	case OpBetween:
+		if len(parts) != 2 {
+			return c, fmt.Errorf("between expects two values")
+		}
+	case OpEq:
+		if len(parts) > 1 {
+			if spec.kind == KindTime {
+				return c, fmt.Errorf("eq expects a single value")
+			}
+			c.Op = OpIn
+		}
+	}
+
+	if spec.kind == KindTime {
+		return parseTimeCondition(c, parts)
+	}
+	for _, part := range parts {
+		v, err := parseValue(spec.kind, strings.TrimSpace(part))
+		if err != nil {
+			return c, err
+		}
+		c.Values = append(c.Values, v)
+	}
+	return c, nil
+}
+
+// parseTimeCondition разбирает значения времени и переводит сравнения с датой без времени
+// в сравнения с границами суток.
+func parseTimeCondition(c Condition, parts []string) (Condition, error) {
+	var dateOnly []bool
+	for _, part := range parts {
+		t, isDate, err := parseTime(strings.TrimSpace(part))
+		if err != nil {
+			return c, err
+		}
+		c.Values = append(c.Values, t)
+		dateOnly = append(dateOnly, isDate)
+	}
+
+	last := len(c.Values) - 1
+	if !dateOnly[last] {
+		return c, nil
+	}
+	nextDay := c.Values[last].(time.Time).AddDate(0, 0, 1)
+	switch c.Op {
+	case OpEq:
+		c.Op, c.Values = OpRange, []interface{}{c.Values[0], nextDay}
+	case OpGt:
+		c.Op, c.Values = OpGte, []interface{}{nextDay}
+	case OpLte:
+		c.Op, c.Values = OpLt, []interface{}{nextDay}
+	case OpBetween:
+		c.Op, c.Values = OpRange, []interface{}{c.Values[0], nextDay}
+	}
+	return c, nil
+}
+
+func isOp(op Op) bool {
+	for _, ops := range kindOps {
+		for _, o := range ops {
+			if o == op {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func allowed(spec field, op Op) bool {
+	if op == OpNull {
+		return spec.nullable
+	}
+	for _, o := range kindOps[spec.kind] {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseValue(kind Kind, s string) (interface{}, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+	if kind == KindInt {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return n, nil
+	}
+	return s, nil
+}
+
+// parseTime разбирает момент времени RFC 3339 или дату ГГГГ-ММ-ДД (тогда dateOnly = true).
+func parseTime(s string) (t time.Time, dateOnly bool, err error) {
+	if s == "" {
+		return t, false, fmt.Errorf("empty value")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse(time.DateOnly, s)
+	if err != nil {
+		return t, false, fmt.Errorf("%q is not an RFC 3339 timestamp or date", s)
+	}
+	return t, true, nil
+}
@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		query string
+		want  []Condition
+	}{
+		{"", nil},
+		{"limit=10&sort=name", nil},
+		{"name=Ив", []Condition{{Field: "name", Op: OpContains, Values: []interface{}{"Ив"}}}},
+		{"name=exact:Иван", []Condition{{Field: "name", Op: OpEq, Values: []interface{}{"Иван"}}}},
+		{"surname=prefix:Ив,Пе", []Condition{{Field: "surname", Op: OpPrefix, Values: []interface{}{"Ив", "Пе"}}}},
+		{"gender=male,female", []Condition{{Field: "gender", Op: OpIn, Values: []interface{}{"male", "female"}}}},
+		{"nationality=not:in:RU,UA", []Condition{{Field: "nationality", Op: OpIn, Not: true, Values: []interface{}{"RU", "UA"}}}},
+		{"patronymic=null:true", []Condition{{Field: "patronymic", Op: OpNull, Values: []interface{}{true}}}},
+		{"age=between:20,30", []Condition{{Field: "age", Op: OpBetween, Values: []interface{}{20, 30}}}},
+		{"age=gte:20&age=lte:30", []Condition{
+			{Field: "age", Op: OpGte, Values: []interface{}{20}},
+			{Field: "age", Op: OpLte, Values: []interface{}{30}},
+		}},
+		{"age_gt=20&age_lt=30", []Condition{
+			{Field: "age", Op: OpGt, Values: []interface{}{20}},
+			{Field: "age", Op: OpLt, Values: []interface{}{30}},
+		}},
+		{"created_at=gte:2024-01-01T10:30:00Z", []Condition{{Field: "created_at", Op: OpGte, Values: []interface{}{ts}}}},
+		{"created_at=2024-01-01T10:30:00Z", []Condition{{Field: "created_at", Op: OpEq, Values: []interface{}{ts}}}},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got, err := Parse(q)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Conditions, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got.Conditions, tt.want)
+		}
+	}
+}
+
+func TestParseDateOnly(t *testing.T) {
+	day, next := date(2024, 1, 1), date(2024, 1, 2)
+
+	tests := []struct {
+		query string
+		want  Condition
+	}{
+		{"created_at=2024-01-01", Condition{Field: "created_at", Op: OpRange, Values: []interface{}{day, next}}},
+		{"created_at=not:2024-01-01", Condition{Field: "created_at", Op: OpRange, Not: true, Values: []interface{}{day, next}}},
+		{"created_at=gt:2024-01-01", Condition{Field: "created_at", Op: OpGte, Values: []interface{}{next}}},
+		{"created_at=gte:2024-01-01", Condition{Field: "created_at", Op: OpGte, Values: []interface{}{day}}},
+		{"created_at=lt:2024-01-01", Condition{Field: "created_at", Op: OpLt, Values: []interface{}{day}}},
+		{"updated_at=lte:2024-01-01", Condition{Field: "updated_at", Op: OpLt, Values: []interface{}{next}}},
+		{"created_at=between:2023-12-01,2024-01-01", Condition{Field: "created_at", Op: OpRange, Values: []interface{}{date(2023, 12, 1), next}}},
+		{"created_at=between:2023-12-01,2024-01-01T00:00:00Z", Condition{Field: "created_at", Op: OpBetween, Values: []interface{}{date(2023, 12, 1), day}}},
+	}
+
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got, err := Parse(q)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if len(got.Conditions) != 1 || !reflect.DeepEqual(got.Conditions[0], tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got.Conditions, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		"age=abc",
+		"age=contains:1",
+		"age=gt:1,2",
+		"age=between:1",
+		"gender=prefix:m",
+		"name=null:true",
+		"patronymic=null:maybe",
+		"created_at=yesterday",
+		"created_at=2024-01-01,2024-01-02",
+		"created_at=range:2024-01-01,2024-01-02",
+		"include_deleted=sometimes",
+		"name=in:a,,b",
+	} {
+		q, _ := url.ParseQuery(query)
+		if _, err := Parse(q); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Parse(%q) error = %v, want %v", query, err, ErrInvalidFilter)
+		}
+	}
+}
+
+func TestParseIncludeDeleted(t *testing.T) {
+	q, _ := url.ParseQuery("include_deleted=true")
+	f, err := Parse(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.IncludeDeleted {
+		t.Error("IncludeDeleted = false, want true")
+	}
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"effective-mobile-task/internal/problem"
+)
+
+// isAdmin проверяет токен администратора в заголовке Authorization: Bearer.
+// Без настроенного токена администраторов нет.
+func (h *PersonHandler) isAdmin(r *http.Request) bool {
+	if h.opts.AdminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.AdminToken)) == 1
+}
+
+// requireAdmin отвечает 403 на запрос удаленных записей без токена администратора.
+func (h *PersonHandler) requireAdmin(w http.ResponseWriter, r *http.Request, includeDeleted bool) bool {
+	if !includeDeleted || h.isAdmin(r) {
+		return true
+	}
+	problem.Error(w, r, "include_deleted requires an admin token", http.StatusForbidden)
+	return false
+}
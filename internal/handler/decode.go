@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/service"
+)
+
+// Ограничения размера тела запроса.
+const (
+	maxBodyBytes     = 64 << 10
+	maxBulkBodyBytes = 4 << 20
+)
+
+var errTrailingData = errors.New("unexpected data after JSON value")
+
+// readBody читает тело запроса не длиннее limit байт.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+}
+
+// decodeBody читает тело не длиннее limit и декодирует его в v так же, как decodeStrict.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any, limit int64) error {
+	data, err := readBody(w, r, limit)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(data, v, "")
+}
+
+// decodeStrict декодирует один JSON-объект в *v. Неизвестные поля дают *service.ValidationError
+// с ними и с нарушениями service.Validate в остальных полях; path - путь объекта в теле
+// для имен полей, например items[3].
+func decodeStrict(data []byte, v any, path string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	// неизвестные поля ищутся сравнением ключей объекта с полями структуры, а не по тексту ошибки
+	if err != nil {
+		if fields := unknownFields(data, reflect.TypeOf(v).Elem(), path); len(fields) > 0 {
+			if json.Unmarshal(data, v) == nil {
+				var verr *service.ValidationError
+				if errors.As(service.Validate(v), &verr) {
+					for _, f := range verr.Fields {
+						fields = append(fields, service.FieldError{Field: fieldPath(path, f.Field), Message: f.Message})
+					}
+				}
+			}
+			return &service.ValidationError{Fields: fields}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return nil
+}
+
+// unknownFields возвращает ключи объекта data, которых нет среди json-полей структуры t.
+func unknownFields(data []byte, t reflect.Type, path string) []service.FieldError {
+	var obj map[string]json.RawMessage
+	if t.Kind() != reflect.Struct || json.Unmarshal(data, &obj) != nil {
+		return nil
+	}
+	known := map[string]bool{}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		switch {
+		case !sf.IsExported() || name == "-":
+			continue
+		case name == "":
+			name = sf.Name
+		}
+		known[strings.ToLower(name)] = true
+	}
+
+	var fields []service.FieldError
+	for _, key := range slices.Sorted(maps.Keys(obj)) {
+		// encoding/json сопоставляет имена без учета регистра
+		if !known[strings.ToLower(key)] {
+			fields = append(fields, service.FieldError{Field: fieldPath(path, key), Message: "is not allowed"})
+		}
+	}
+	return fields
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// writeDecodeError отвечает на ошибку readBody, decodeBody или decodeStrict: 413 на слишком
+// большое тело, 422 на неизвестные поля и 400 на некорректный JSON.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error, path string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		problem.Error(w, r, "Request body is too large", http.StatusRequestEntityTooLarge)
+	case errors.Is(err, service.ErrValidation):
+		problem.Error(w, r, err.Error(), http.StatusUnprocessableEntity, fieldErrors(err)...)
+	default:
+		invalidBody(w, r, err, path)
+	}
+}
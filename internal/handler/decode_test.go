@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/service"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		path       string
+		want       models.CreatePersonRequest
+		wantFields []service.FieldError
+		wantErr    bool
+	}{
+		{
+			name: "known fields",
+			data: `{"name":"Ivan","surname":"Ivanov"}`,
+			want: models.CreatePersonRequest{Name: "Ivan", Surname: "Ivanov"},
+		},
+		{
+			name: "field names are case-insensitive",
+			data: `{"Name":"Ivan","SURNAME":"Ivanov"}`,
+			want: models.CreatePersonRequest{Name: "Ivan", Surname: "Ivanov"},
+		},
+		{
+			name:       "unknown fields are reported with violations of known ones",
+			data:       `{"name":"","surname":"Ivanov","age":30,"email":"x"}`,
+			wantFields: []service.FieldError{{Field: "age", Message: "is not allowed"}, {Field: "email", Message: "is not allowed"}, {Field: "name", Message: "is required"}},
+		},
+		{
+			name:       "path prefixes field names",
+			data:       `{"name":"Ivan","surname":"Ivanov","extra":1}`,
+			path:       "items[2]",
+			wantFields: []service.FieldError{{Field: "items[2].extra", Message: "is not allowed"}},
+		},
+		{
+			name:    "wrong type is a decode error",
+			data:    `{"name":5}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed JSON",
+			data:    `{"name":`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			data:    `{"name":"Ivan"} {}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.CreatePersonRequest
+			err := decodeStrict([]byte(tt.data), &got, tt.path)
+
+			var verr *service.ValidationError
+			switch {
+			case tt.wantFields != nil:
+				if !errors.As(err, &verr) {
+					t.Fatalf("decodeStrict() = %v, want *service.ValidationError", err)
+				}
+				if !slices.Equal(verr.Fields, tt.wantFields) {
+					t.Errorf("fields = %v, want %v", verr.Fields, tt.wantFields)
+				}
+			case tt.wantErr:
+				if err == nil || errors.As(err, &verr) {
+					t.Errorf("decodeStrict() = %v, want a decode error", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("decodeStrict() = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("decoded %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownFieldsSkipsHiddenFields(t *testing.T) {
+	var v models.UpdatePersonRequest
+	err := decodeStrict([]byte(`{"name":"Ivan","NameOriginal":"x"}`), &v, "")
+	var verr *service.ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != "NameOriginal" {
+		t.Errorf("decodeStrict() = %v, want NameOriginal to be rejected", err)
+	}
+}
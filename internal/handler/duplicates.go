@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/problem"
+	"go.uber.org/zap"
+)
+
+// Duplicates godoc
+// @Summary Поиск возможных дубликатов
+// @Description Группы людей с похожими ФИО. Оценка группы учитывает сходство ФИО и совпадение пола, национальности и возраста.
+// @Tags persons
+// @Produce json
+// @Param threshold query number false "Минимальное сходство ФИО, от 0 до 1" default(0.6)
+// @Param limit query int false "Наибольшее число групп" default(20)
+// @Success 200 {array} models.DuplicateCluster
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /persons/duplicates [get]
+func (h *PersonHandler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	threshold := 0.6
+	if v := q.Get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t > 1 {
+			problem.Error(w, r, "Invalid threshold, expected number in (0, 1]", http.StatusBadRequest)
+			return
+		}
+		threshold = t
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, 100)
+
+	clusters, err := h.service.FindDuplicates(r.Context(), threshold, limit)
+	if err != nil {
+		h.log(r).Error("Failed to find duplicates", zap.Error(err))
+		h.writeError(w, r, err, "Failed to find duplicates")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusters)
+}
+
+// Merge godoc
+// @Summary Слияние дубликатов
+// @Description Поглощает merged_ids выжившим survivor_id. Значение каждого поля выбирается правилом из fields,
+// @Description поглощенные удаляются, слияние записывается в историю всех участников,
+// @Description а GET /persons/{id} поглощенного перенаправляет на выжившего.
+// @Description If-Match проверяется у выжившего.
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param input body models.MergePersonsRequest true "Участники и правила слияния"
+// @Param If-Match header string false "ETag выжившего"
+// @Success 200 {object} models.Person
+// @Failure 400,404,409,412,413,422,428,500 {object} problem.Problem
+// @Router /persons/merge [post]
+func (h *PersonHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	var req models.MergePersonsRequest
+	if err := decodeBody(w, r, &req, maxBodyBytes); err != nil {
+		h.log(r).Error("Failed to decode merge request", zap.Error(err))
+		writeDecodeError(w, r, err, "")
+		return
+	}
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	person, err := h.service.Merge(r.Context(), req, version)
+	if err != nil {
+		h.log(r).Error("Failed to merge persons", zap.String("survivor_id", req.SurvivorID.String()), zap.Error(err))
+		h.writeError(w, r, err, "Failed to merge persons")
+		return
+	}
+
+	h.log(r).Info("Merged persons", zap.String("survivor_id", person.ID.String()), zap.Int("merged", len(req.MergedIDs)))
+	setETag(w, person)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
+}
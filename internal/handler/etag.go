@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/problem"
+)
+
+func setETag(w http.ResponseWriter, p *models.Person) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(p.Version)+`"`)
+}
+
+// ifMatchVersion возвращает версию из заголовка If-Match (0 - проверка не нужна).
+// Тег, который не может совпасть ни с одной версией (слабый, список, мусор), дает 412.
+func (h *PersonHandler) ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch header {
+	case "":
+		if h.opts.RequireIfMatch {
+			problem.Error(w, r, "If-Match header is required", http.StatusPreconditionRequired)
+			return 0, false
+		}
+		return 0, true
+	case "*":
+		return 0, true
+	}
+
+	if len(header) >= 2 && header[0] == '"' && header[len(header)-1] == '"' {
+		if version, err := strconv.Atoi(header[1 : len(header)-1]); err == nil && version > 0 {
+			return version, true
+		}
+	}
+	problem.Error(w, r, "Person was modified by another request", http.StatusPreconditionFailed)
+	return 0, false
+}
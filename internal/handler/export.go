@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"effective-mobile-task/internal/export"
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Export godoc
+// @Summary Выгрузка людей
+// @Description Выгружает всех людей под фильтрами списка в CSV, NDJSON или XLSX. Строки передаются потоком по мере чтения из базы.
+// @Description Формат задается параметром format или заголовком Accept, по умолчанию CSV.
+// @Description Фильтры и sort - как в GET /persons.
+// @Tags persons
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Формат" Enums(csv, ndjson, xlsx)
+// @Param columns query string false "Столбцы через запятую: id, name, surname, patronymic, name_original, surname_original, patronymic_original, age, birth_year, gender, nationality, created_at, updated_at, version, deleted_at" default(id,name,surname,patronymic,age,gender,nationality,created_at,updated_at)
+// @Param sort query string false "Поля сортировки, как в GET /persons" default(-created_at)
+// @Param name query string false "Имя, синтаксис как в GET /persons"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param age query string false "Текущий возраст"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param created_at query string false "Дата создания"
+// @Param updated_at query string false "Дата изменения"
+// @Param include_deleted query bool false "Включить удаленных (только администратор)"
+// @Success 200 {string} string "Файл выгрузки"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 406 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security AdminToken
+// @Router /persons/export [get]
+func (h *PersonHandler) Export(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format, ok := exportFormat(q.Get("format"), r.Header.Get("Accept"))
+	if !ok {
+		if q.Has("format") {
+			problem.Error(w, r, "Invalid format, expected csv, ndjson or xlsx", http.StatusBadRequest)
+		} else {
+			problem.Error(w, r, "Supported formats: text/csv, application/x-ndjson, "+export.ContentTypes[export.FormatXLSX], http.StatusNotAcceptable)
+		}
+		return
+	}
+
+	columns, err := export.ParseColumns(q.Get("columns"))
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters, err := filter.Parse(q)
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.requireAdmin(w, r, filters.IncludeDeleted) {
+		return
+	}
+	sort, err := repository.ParseSort(q.Get("sort"))
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var out io.Writer = w
+	if h.opts.ExportWriteTimeout > 0 {
+		out = &deadlineWriter{w: w, rc: http.NewResponseController(w), timeout: h.opts.ExportWriteTimeout}
+	}
+
+	w.Header().Set("Content-Type", export.ContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="persons-`+time.Now().Format("20060102-150405")+"."+format+`"`)
+	ew, err := export.NewWriter(out, format, columns)
+	if err == nil {
+		err = h.service.Export(r.Context(), filters, sort, func(p models.Person) error {
+			return ew.Write(p)
+		})
+	}
+	if err == nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		h.log(r).Error("Failed to export persons", zap.String("format", format), zap.Error(err))
+		if ew == nil || !ew.Started() {
+			w.Header().Del("Content-Disposition")
+			h.writeError(w, r, err, "Failed to export persons")
+			return
+		}
+		// заголовки уже отправлены: обрываем ответ, чтобы клиент не принял неполный файл за целый
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// deadlineWriter продлевает срок записи ответа перед каждой порцией: выгрузка может идти
+// дольше WriteTimeout сервера, но клиент, переставший читать, обрывает ее через timeout.
+type deadlineWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.rc.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, err
+	}
+	return d.w.Write(p)
+}
+
+// exportFormat выбирает формат по параметру format, а без него - по первому подходящему типу из Accept.
+func exportFormat(param, accept string) (string, bool) {
+	if param != "" {
+		_, ok := export.ContentTypes[param]
+		return param, ok
+	}
+	if accept == "" {
+		return export.FormatCSV, true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "*/*", "text/*", "text/csv":
+			return export.FormatCSV, true
+		case "application/x-ndjson", "application/jsonl":
+			return export.FormatNDJSON, true
+		case export.ContentTypes[export.FormatXLSX]:
+			return export.FormatXLSX, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/repository"
+	"effective-mobile-task/internal/service"
+	"go.uber.org/zap"
+)
+
+// idempotentHeaders - заголовки ответа, которые сохраняются и повторяются вместе с телом.
+var idempotentHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency выполняет запрос с заголовком Idempotency-Key один раз. Ответ сохраняется,
+// и повторы с тем же ключом получают его с заголовком Idempotent-Replayed: true.
+// Повтор, пока первый запрос выполняется, получает 409, а тот же ключ с другим телом - 422.
+// Ответы 5xx не сохраняются. Ключ действует для метода, пути и автора из X-Actor.
+func Idempotency(s *service.IdempotencyService, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := readBody(w, r, maxBulkBodyBytes)
+			if err != nil {
+				writeDecodeError(w, r, err, "")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := r.Method + " " + r.URL.Path + " " + actor.FromContext(r.Context())
+			request := append([]byte(r.URL.RawQuery+"\n"), body...)
+			stored, err := s.Begin(r.Context(), scope, key, request)
+			switch {
+			case errors.Is(err, repository.ErrKeyInProgress):
+				w.Header().Set("Retry-After", "1")
+				problem.Write(w, r, problem.Problem{Type: problem.TypeKeyInProgress, Status: http.StatusConflict,
+					Detail: "Request with the same Idempotency-Key is in progress"})
+				return
+			case errors.Is(err, repository.ErrKeyMismatch):
+				problem.Write(w, r, problem.Problem{Type: problem.TypeKeyReused, Status: http.StatusUnprocessableEntity,
+					Detail: "Idempotency-Key was used for a different request"})
+				return
+			case errors.Is(err, service.ErrValidation):
+				problem.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			case err != nil:
+				requestLogger(r, logger).Error("Failed to acquire idempotency key", zap.String("key", key), zap.Error(err))
+				problem.Error(w, r, "Failed to process request", http.StatusInternalServerError)
+				return
+			}
+			if stored != nil {
+				for name, value := range stored.Header {
+					w.Header().Set(name, value)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+				return
+			}
+
+			// ответ сохраняется и после отключения клиента: повтор должен получить результат
+			ctx := context.WithoutCancel(r.Context())
+			rec := &responseRecorder{ResponseWriter: w}
+			saved := false
+			defer func() {
+				if !saved {
+					if err := s.Abort(ctx, scope, key); err != nil {
+						requestLogger(r, logger).Error("Failed to release idempotency key", zap.String("key", key), zap.Error(err))
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			resp := models.IdempotentResponse{StatusCode: rec.status, Header: map[string]string{}, Body: rec.body.Bytes()}
+			for _, name := range idempotentHeaders {
+				if value := w.Header().Get(name); value != "" {
+					resp.Header[name] = value
+				}
+			}
+			if err := s.Complete(ctx, scope, key, resp); err != nil {
+				requestLogger(r, logger).Error("Failed to save idempotent response", zap.String("key", key), zap.Error(err))
+				return
+			}
+			saved = true
+		})
+	}
+}
+
+// responseRecorder передает ответ клиенту, попутно запоминая статус и тело.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/repository"
+	"effective-mobile-task/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxImportSize - наибольший размер загружаемого файла.
+const maxImportSize = 32 << 20
+
+type ImportHandler struct {
+	service *service.ImportService
+	logger  *zap.Logger
+}
+
+func NewImportHandler(s *service.ImportService, logger *zap.Logger) *ImportHandler {
+	return &ImportHandler{service: s, logger: logger}
+}
+
+// log возвращает логгер запроса с request_id.
+func (h *ImportHandler) log(r *http.Request) *zap.Logger {
+	return requestLogger(r, h.logger)
+}
+
+func (h *ImportHandler) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		problem.Error(w, r, "Import not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrValidation):
+		problem.Error(w, r, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		problem.Error(w, r, msg, http.StatusInternalServerError)
+	}
+}
+
+// Create godoc
+// @Summary Загрузка файла для импорта людей
+// @Description Принимает CSV или NDJSON файлом в multipart/form-data (поле file) или телом запроса.
+// @Description Файл проверяется сразу, строки обогащаются и сохраняются в фоне; прогресс - в GET /imports/{id}.
+// @Description Формат определяется параметром format, расширением файла или Content-Type.
+// @Tags imports
+// @Accept multipart/form-data,text/csv,application/x-ndjson
+// @Produce json
+// @Param file formData file false "Файл"
+// @Param format query string false "Формат" Enums(csv, ndjson)
+// @Param mapping query string false "JSON: поле человека -> столбец, например {\"name\":\"Имя\",\"surname\":\"Фамилия\"}"
+// @Param delimiter query string false "Разделитель CSV" default(,)
+// @Success 202 {object} models.Import
+// @Header 202 {string} Location "Адрес импорта"
+// @Failure 400,413,422,500 {object} problem.Problem
+// @Router /imports [post]
+func (h *ImportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var req service.ImportRequest
+	var body io.Reader = r.Body
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	req.Format = importFormat(mediaType, "")
+	if mediaType == "multipart/form-data" {
+		file, fh, err := r.FormFile("file")
+		if err != nil {
+			h.writeUploadError(w, r, err)
+			return
+		}
+		defer file.Close()
+		body = file
+		partType, _, _ := mime.ParseMediaType(fh.Header.Get("Content-Type"))
+		req.Format = importFormat(partType, fh.Filename)
+	}
+	if format := r.FormValue("format"); format != "" {
+		req.Format = format
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		h.writeUploadError(w, r, err)
+		return
+	}
+	req.Data = data
+	req.Delimiter = r.FormValue("delimiter")
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			problem.Error(w, r, "Invalid mapping, expected JSON object", http.StatusBadRequest)
+			return
+		}
+	}
+
+	imp, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		h.log(r).Error("Failed to create import", zap.Error(err))
+		h.writeError(w, r, err, "Failed to create import")
+		return
+	}
+
+	h.log(r).Info("Created import", zap.String("id", imp.ID.String()), zap.Int("rows", imp.TotalRows))
+	w.Header().Set("Location", "/imports/"+imp.ID.String())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(imp)
+}
+
+func (h *ImportHandler) writeUploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		problem.Error(w, r, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	h.log(r).Error("Failed to read import upload", zap.Error(err))
+	problem.Error(w, r, "Invalid upload", http.StatusBadRequest)
+}
+
+// importFormat угадывает формат по типу содержимого или расширению файла.
+func importFormat(mediaType, filename string) string {
+	switch {
+	case mediaType == "text/csv", strings.EqualFold(path.Ext(filename), ".csv"):
+		return models.ImportCSV
+	case mediaType == "application/x-ndjson", strings.EqualFold(path.Ext(filename), ".ndjson"), strings.EqualFold(path.Ext(filename), ".jsonl"):
+		return models.ImportNDJSON
+	}
+	return ""
+}
+
+// Get godoc
+// @Summary Состояние импорта
+// @Tags imports
+// @Produce json
+// @Param id path string true "UUID импорта"
+// @Success 200 {object} models.Import
+// @Failure 400,404,500 {object} problem.Problem
+// @Router /imports/{id} [get]
+func (h *ImportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.importID(w, r)
+	if !ok {
+		return
+	}
+
+	imp, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.log(r).Error("Failed to get import", zap.String("id", id.String()), zap.Error(err))
+		h.writeError(w, r, err, "Failed to get import")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imp)
+}
+
+// Errors godoc
+// @Summary Строки импорта с ошибками
+// @Description CSV с номером строки исходного файла, исходными столбцами и текстом ошибки
+// @Tags imports
+// @Produce text/csv
+// @Param id path string true "UUID импорта"
+// @Success 200 {string} string "CSV"
+// @Failure 400,404,500 {object} problem.Problem
+// @Router /imports/{id}/errors [get]
+func (h *ImportHandler) Errors(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.importID(w, r)
+	if !ok {
+		return
+	}
+
+	imp, errs, err := h.service.Errors(r.Context(), id)
+	if err != nil {
+		h.log(r).Error("Failed to get import errors", zap.String("id", id.String()), zap.Error(err))
+		h.writeError(w, r, err, "Failed to get import errors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="import-`+id.String()+`-errors.csv"`)
+	cw := csv.NewWriter(w)
+	if imp.Delimiter != "" {
+		cw.Comma = []rune(imp.Delimiter)[0]
+	}
+	cw.Write(append(append([]string{"row"}, imp.Columns...), "error"))
+	for _, e := range errs {
+		cw.Write(append(append([]string{strconv.Itoa(e.Row)}, e.Record...), e.Error))
+	}
+	cw.Flush()
+}
+
+func (h *ImportHandler) importID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	idStr := mux.Vars(r)["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
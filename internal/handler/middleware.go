@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/requestid"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxActorLength - длина колонок changed_by, created_by и merged_by, в которые пишется X-Actor.
+const maxActorLength = 150
+
+// maxRequestIDLength ограничивает длину X-Request-ID клиента; более длинный заменяется новым.
+const maxRequestIDLength = 128
+
+// Actor берет автора изменений для истории из заголовка X-Actor.
+// Заголовок задает клиент и сервер его не проверяет, поэтому автор в истории справочный;
+// отклоняется только значение, которое не поместится в историю.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := r.Header.Get("X-Actor"); name != "" {
+			if !utf8.ValidString(name) || utf8.RuneCountInString(name) > maxActorLength {
+				problem.Error(w, r, fmt.Sprintf("X-Actor must be valid UTF-8 of at most %d characters", maxActorLength), http.StatusBadRequest)
+				return
+			}
+			r = r.WithContext(actor.WithName(r.Context(), name))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type loggerKey struct{}
+
+// RequestID берет идентификатор запроса из X-Request-ID или создает новый
+// и возвращает его в заголовке ответа и в request_id ошибок. Обработчики пишут
+// в лог через requestLogger - logger с тем же request_id.
+func RequestID(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-ID")
+			if id == "" || len(id) > maxRequestIDLength {
+				id = uuid.NewString()
+			}
+			w.Header().Set("X-Request-ID", id)
+			ctx := requestid.WithID(r.Context(), id)
+			ctx = context.WithValue(ctx, loggerKey{}, logger.With(zap.String("request_id", id)))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// requestLogger возвращает логгер запроса из RequestID, а без него - fallback.
+func requestLogger(r *http.Request, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := r.Context().Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// NotFound отвечает 404 на неизвестный путь.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	problem.Error(w, r, "No route for "+r.URL.Path, http.StatusNotFound)
+}
+
+// MethodNotAllowed отвечает 405 на неподдерживаемый путем метод и перечисляет
+// в заголовке Allow методы маршрутов router, подходящих под путь.
+func MethodNotAllowed(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			methods, err := route.GetMethods()
+			if err != nil {
+				return nil
+			}
+			for _, method := range methods {
+				probe := r.Clone(r.Context())
+				probe.Method = method
+				if !slices.Contains(allowed, method) && route.Match(probe, &mux.RouteMatch{}) {
+					allowed = append(allowed, method)
+				}
+			}
+			return nil
+		})
+		slices.Sort(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		problem.Write(w, r, problem.Problem{Status: http.StatusMethodNotAllowed, Detail: "Method " + r.Method + " is not allowed"})
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"effective-mobile-task/internal/actor"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMethodNotAllowedSetsAllow(t *testing.T) {
+	ok := func(http.ResponseWriter, *http.Request) {}
+	r := mux.NewRouter()
+	r.HandleFunc("/persons", ok).Methods("GET")
+	r.HandleFunc("/persons", ok).Methods("POST")
+	r.HandleFunc("/persons/{id}", ok).Methods("GET")
+	r.HandleFunc("/persons/{id}", ok).Methods("PUT", "PATCH")
+	r.HandleFunc("/persons/{id}", ok).Methods("DELETE")
+	r.HandleFunc("/persons/{id}/restore", ok).Methods("POST")
+	r.MethodNotAllowedHandler = MethodNotAllowed(r)
+
+	tests := []struct {
+		method, path string
+		wantAllow    string
+	}{
+		{"DELETE", "/persons", "GET, POST"},
+		{"POST", "/persons/42", "DELETE, GET, PATCH, PUT"},
+		{"GET", "/persons/42/restore", "POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want 405", w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestRequestIDAttachesLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	fallback := zap.NewNop()
+	h := RequestID(zap.New(core))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestLogger(r, fallback).Info("handled")
+	}))
+
+	req := httptest.NewRequest("GET", "/persons", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["request_id"]; got != "req-1" {
+		t.Errorf("request_id = %v, want req-1", got)
+	}
+
+	if got := requestLogger(httptest.NewRequest("GET", "/", nil), fallback); got != fallback {
+		t.Error("requestLogger outside RequestID must return the fallback")
+	}
+}
+
+func TestActor(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantActor  string
+	}{
+		{"no header", "", http.StatusOK, actor.Anonymous},
+		{"short name", "Иван Иванов", http.StatusOK, "Иван Иванов"},
+		{"longest name", strings.Repeat("я", maxActorLength), http.StatusOK, strings.Repeat("я", maxActorLength)},
+		{"too long", strings.Repeat("a", maxActorLength+1), http.StatusBadRequest, ""},
+		{"invalid UTF-8", "\xff\xfe", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := Actor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = actor.FromContext(r.Context())
+			}))
+			req := httptest.NewRequest("POST", "/persons", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Actor", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got != tt.wantActor {
+				t.Errorf("actor = %q, want %q", got, tt.wantActor)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"effective-mobile-task/internal/models"
+)
+
+// writePage проставляет ссылки на соседние страницы и отдает страницу конвертом
+// или массивом; X-Total-Count и Link (RFC 8288) выставляются в обоих случаях.
+func writePage(w http.ResponseWriter, r *http.Request, page *models.PersonPage, envelope bool) {
+	if page.Items == nil {
+		page.Items = []models.Person{}
+	}
+
+	if page.Offset != nil {
+		offset, n := *page.Offset, len(page.Items)
+		hasNext := n == page.Limit && (page.Total == nil || page.TotalEstimated || int64(offset+n) < *page.Total)
+		if hasNext {
+			page.Next = pageLink(r, "offset", strconv.Itoa(offset+n))
+		}
+		if offset > 0 {
+			page.Prev = pageLink(r, "offset", strconv.Itoa(max(offset-page.Limit, 0)))
+		}
+	} else {
+		if page.NextCursor != "" {
+			page.Next = pageLink(r, "cursor", page.NextCursor)
+		}
+		if page.PrevCursor != "" {
+			page.Prev = pageLink(r, "cursor", page.PrevCursor)
+		}
+	}
+
+	if page.Total != nil {
+		w.Header().Set("X-Total-Count", strconv.FormatInt(*page.Total, 10))
+		if page.TotalEstimated {
+			w.Header().Set("X-Total-Count-Estimated", "true")
+		}
+	}
+	var links []string
+	for _, l := range []struct{ rel, href string }{{"next", page.Next}, {"prev", page.Prev}} {
+		if l.href != "" {
+			links = append(links, "<"+l.href+`>; rel="`+l.rel+`"`)
+		}
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if envelope {
+		json.NewEncoder(w).Encode(page)
+	} else {
+		json.NewEncoder(w).Encode(page.Items)
+	}
+}
+
+// pageLink возвращает адрес текущего запроса с замененным параметром key.
+func pageLink(r *http.Request, key, value string) string {
+	q := r.URL.Query()
+	q.Set(key, value)
+	return r.URL.Path + "?" + q.Encode()
+}
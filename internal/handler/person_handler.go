@@ -2,73 +2,274 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/jsonpatch"
 	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/repository"
 	"effective-mobile-task/internal/service"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
+type Options struct {
+	// RequireIfMatch запрещает PUT/PATCH/DELETE без заголовка If-Match.
+	RequireIfMatch bool
+	// AdminToken открывает include_deleted запросам с Authorization: Bearer <токен>.
+	AdminToken string
+	// ExportWriteTimeout - срок записи каждой порции выгрузки; 0 - вся выгрузка в пределах WriteTimeout сервера.
+	ExportWriteTimeout time.Duration
+}
+
 type PersonHandler struct {
 	service *service.PersonService
 	logger  *zap.Logger
+	opts    Options
+}
+
+func NewPersonHandler(s *service.PersonService, logger *zap.Logger, opts Options) *PersonHandler {
+	return &PersonHandler{service: s, logger: logger, opts: opts}
 }
 
-func NewPersonHandler(s *service.PersonService, logger *zap.Logger) *PersonHandler {
-	return &PersonHandler{service: s, logger: logger}
+// log возвращает логгер запроса с request_id.
+func (h *PersonHandler) log(r *http.Request) *zap.Logger {
+	return requestLogger(r, h.logger)
+}
+
+// writeError отвечает 4xx для ошибок данных и 500 для остальных ошибок (недоступность БД и т.п.).
+func (h *PersonHandler) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		problem.Error(w, r, "Person not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrVersionMismatch):
+		problem.Error(w, r, "Person was modified by another request", http.StatusPreconditionFailed)
+	case errors.Is(err, repository.ErrConflict):
+		problem.Error(w, r, "Person already exists", http.StatusConflict)
+	case errors.Is(err, repository.ErrConstraint):
+		problem.Error(w, r, "Invalid person data", http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrValidation), errors.Is(err, jsonpatch.ErrPathNotFound):
+		problem.Error(w, r, err.Error(), http.StatusUnprocessableEntity, fieldErrors(err)...)
+	case errors.Is(err, jsonpatch.ErrInvalidPatch), errors.Is(err, repository.ErrInvalidCursor):
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		problem.Error(w, r, err.Error(), http.StatusConflict)
+	default:
+		problem.Error(w, r, msg, http.StatusInternalServerError)
+	}
 }
 
 // Create godoc
 // @Summary Создание нового человека
-// @Description Обогащает ФИО через внешние API и сохраняет в БД
+// @Description Обогащает ФИО через внешние API и сохраняет в БД.
+// @Description Если человек с тем же ФИО уже есть, возвращается 409 с его id в existing_id и заголовке Location,
+// @Description а с on_duplicate=return - 200 с существующей записью.
+// @Description ФИО приводится к каноническому виду (лишние пробелы, Unicode NFC, заглавные буквы с учетом дефисов
+// @Description и частиц в фамилии вроде "de la Cruz"), присланные значения сохраняются в *_original.
+// @Description ФИО - буквы латиницы или кириллицы, пробелы, дефисы и апострофы,
+// @Description не длиннее 150 символов. Неизвестные поля и все нарушения перечисляются в errors ответа 422.
 // @Tags persons
 // @Accept json
 // @Produce json
 // @Param input body models.CreatePersonRequest true "Данные человека"
+// @Param on_duplicate query string false "Что делать при дубликате" Enums(error, return) default(error)
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ, а параллельный повтор - 409"
 // @Success 201 {object} models.Person
-// @Failure 400 {object} string
-// @Failure 500 {object} string
+// @Success 200 {object} models.Person "Существующий дубликат при on_duplicate=return"
+// @Header 201 {string} Idempotent-Replayed "true, если ответ сохранен для прошлого запроса с тем же ключом"
+// @Header 409 {string} Location "Адрес существующего человека"
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /persons [post]
 func (h *PersonHandler) Create(w http.ResponseWriter, r *http.Request) {
+	onDuplicate := r.URL.Query().Get("on_duplicate")
+	if onDuplicate != "" && onDuplicate != "error" && onDuplicate != "return" {
+		problem.Error(w, r, "Invalid on_duplicate, expected error or return", http.StatusBadRequest)
+		return
+	}
+
 	var req models.CreatePersonRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode create request", zap.Error(err))
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if err := decodeBody(w, r, &req, maxBodyBytes); err != nil {
+		h.log(r).Error("Failed to decode create request", zap.Error(err))
+		writeDecodeError(w, r, err, "")
 		return
 	}
 
 	person, err := h.service.Create(r.Context(), req)
+	var duplicate *service.DuplicateError
+	if errors.As(err, &duplicate) {
+		existing := duplicate.Existing
+		h.log(r).Info("Duplicate person", zap.String("existing_id", existing.ID.String()))
+		if onDuplicate == "return" {
+			setETag(w, existing)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(existing)
+			return
+		}
+		w.Header().Set("Location", "/persons/"+existing.ID.String())
+		problem.Write(w, r, problem.Problem{
+			Type:       problem.TypeDuplicate,
+			Status:     http.StatusConflict,
+			Detail:     "Person already exists: " + existing.ID.String(),
+			ExistingID: existing.ID.String(),
+		})
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to create person", zap.Error(err))
-		http.Error(w, "Failed to create person", http.StatusInternalServerError)
+		h.log(r).Error("Failed to create person", zap.Error(err))
+		h.writeError(w, r, err, "Failed to create person")
 		return
 	}
 
-	h.logger.Info("Created person", zap.String("id", person.ID.String()))
+	h.log(r).Info("Created person", zap.String("id", person.ID.String()))
+	setETag(w, person)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(person)
 }
 
+// maxBulkItems - наибольшее число людей в одном запросе POST /persons/bulk.
+const maxBulkItems = 1000
+
+// Bulk godoc
+// @Summary Массовое создание людей
+// @Description Принимает JSON-массив или NDJSON (Content-Type: application/x-ndjson), не больше 1000 элементов.
+// @Description Возвращает статус каждого элемента: created, duplicate, validation_error, enrichment_error, skipped.
+// @Description Дубликат элемента пачки указывается в duplicate_of, уже сохраненного человека - в existing_id.
+// @Description В режиме atomic при любой ошибке не сохраняется никто и ответ 422, в режиме best_effort сохраняются корректные элементы.
+// @Tags persons
+// @Accept json
+// @Accept application/x-ndjson
+// @Produce json
+// @Param input body []models.CreatePersonRequest true "Люди"
+// @Param mode query string false "Режим" Enums(best_effort, atomic) default(best_effort)
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернет сохраненный ответ"
+// @Success 200 {array} models.BulkItemResult
+// @Header 200 {string} Idempotent-Replayed "true, если ответ сохранен для прошлого запроса с тем же ключом"
+// @Failure 400,409,413,422,500 {object} problem.Problem
+// @Router /persons/bulk [post]
+func (h *PersonHandler) Bulk(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "best_effort" && mode != "atomic" {
+		problem.Error(w, r, "Invalid mode, expected best_effort or atomic", http.StatusBadRequest)
+		return
+	}
+	atomic := mode == "atomic"
+
+	var items []json.RawMessage
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-ndjson" {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBulkBodyBytes))
+		for len(items) <= maxBulkItems {
+			var item json.RawMessage
+			if err := dec.Decode(&item); err == io.EOF {
+				break
+			} else if err != nil {
+				h.log(r).Error("Failed to decode bulk request", zap.Error(err))
+				writeDecodeError(w, r, err, fmt.Sprintf("items[%d]", len(items)))
+				return
+			}
+			items = append(items, item)
+		}
+	} else if err := decodeBody(w, r, &items, maxBulkBodyBytes); err != nil {
+		h.log(r).Error("Failed to decode bulk request", zap.Error(err))
+		writeDecodeError(w, r, err, "")
+		return
+	}
+	if len(items) > maxBulkItems {
+		problem.Error(w, r, fmt.Sprintf("Too many items, at most %d allowed", maxBulkItems), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// неизвестные поля собираются по всем элементам, остальные ошибки разбора прерывают запрос
+	reqs := make([]models.CreatePersonRequest, len(items))
+	unknown := &service.ValidationError{}
+	for i, item := range items {
+		path := fmt.Sprintf("items[%d]", i)
+		err := decodeStrict(item, &reqs[i], path)
+		var verr *service.ValidationError
+		if errors.As(err, &verr) {
+			unknown.Fields = append(unknown.Fields, verr.Fields...)
+		} else if err != nil {
+			h.log(r).Error("Failed to decode bulk request", zap.Error(err))
+			writeDecodeError(w, r, err, path)
+			return
+		}
+	}
+	if len(unknown.Fields) > 0 {
+		writeDecodeError(w, r, unknown, "")
+		return
+	}
+
+	results, err := h.service.CreateBulk(r.Context(), reqs, atomic)
+	if err != nil {
+		h.log(r).Error("Failed to create persons in bulk", zap.Int("items", len(reqs)), zap.Error(err))
+		h.writeError(w, r, err, "Failed to create persons")
+		return
+	}
+
+	status := http.StatusOK
+	created := 0
+	for _, res := range results {
+		if res.Status == models.BulkCreated {
+			created++
+		}
+	}
+	if atomic && created < len(results) {
+		status = http.StatusUnprocessableEntity
+	}
+	h.log(r).Info("Created persons in bulk", zap.Int("items", len(reqs)), zap.Int("created", created))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
+
 // List godoc
 // @Summary Получить список людей
-// @Description Получить список людей с фильтрами и пагинацией
+// @Description Получить список людей с фильтрами и пагинацией.
+// @Description С параметром cursor (пустое значение - первая страница) включается пагинация курсорами.
+// @Description Ответ - массив, а с envelope=true или cursor - models.PersonPage.
+// @Description Общее количество и ссылки на соседние страницы также отдаются в X-Total-Count и Link.
+// @Description По умолчанию общее количество - оценка (X-Total-Count-Estimated: true), точный подсчет - count=exact.
 // @Tags persons
 // @Produce json
 // @Param limit query int false "Лимит"
 // @Param offset query int false "Смещение"
-// @Param name query string false "Имя"
-// @Param surname query string false "Фамилия"
-// @Param age query int false "Возраст"
+// @Param cursor query string false "Курсор из next_cursor или prev_cursor"
+// @Param envelope query bool false "Вернуть models.PersonPage вместо массива"
+// @Param count query string false "Подсчет общего количества: exact - точно, estimated - оценка планировщика, none - без подсчета" Enums(exact, estimated, none) default(estimated)
+// @Param sort query string false "Поля сортировки через запятую, минус - по убыванию: name, surname, patronymic, age, gender, nationality, created_at, updated_at" default(-created_at)
+// @Param name query string false "Имя: [not:][contains|prefix|exact|in:]значения через запятую"
+// @Param surname query string false "Фамилия, синтаксис как у name"
+// @Param patronymic query string false "Отчество, синтаксис как у name, а также null:true|false"
+// @Param age query string false "Текущий возраст: [not:][eq|in|gt|gte|lt|lte|between:]значения, например between:20,30"
 // @Param age_gt query int false "Возраст больше"
 // @Param age_lt query int false "Возраст меньше"
-// @Param gender query string false "Пол"
-// @Param nationality query string false "Национальность"
+// @Param gender query string false "Пол: [not:][eq|in:]значения через запятую"
+// @Param nationality query string false "Национальность, синтаксис как у gender"
+// @Param created_at query string false "Дата создания: [not:][eq|gt|gte|lt|lte|between:]RFC 3339 или ГГГГ-ММ-ДД (целые сутки по UTC)"
+// @Param updated_at query string false "Дата изменения, синтаксис как у created_at"
+// @Param include_deleted query bool false "Включить удаленных (только администратор)"
 // @Success 200 {array} models.Person
-// @Failure 500 {object} string
+// @Header 200 {integer} X-Total-Count "Общее количество записей"
+// @Header 200 {string} Link "Ссылки next и prev"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security AdminToken
 // @Router /persons [get]
 func (h *PersonHandler) List(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
@@ -81,22 +282,101 @@ func (h *PersonHandler) List(w http.ResponseWriter, r *http.Request) {
 		offset = 0
 	}
 
-	filters := map[string]string{}
-	for _, key := range []string{"name", "surname", "gender", "nationality", "age", "age_gt", "age_lt"} {
-		if val := q.Get(key); val != "" {
-			filters[key] = val
+	// точный COUNT(*) на большой таблице дорог, поэтому по умолчанию отдается оценка
+	countMode := q.Get("count")
+	switch countMode {
+	case "":
+		countMode = "estimated"
+	case "exact", "estimated", "none":
+	default:
+		problem.Error(w, r, "Invalid count, expected exact, estimated or none", http.StatusBadRequest)
+		return
+	}
+
+	filters, err := filter.Parse(q)
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.requireAdmin(w, r, filters.IncludeDeleted) {
+		return
+	}
+
+	sort, err := repository.ParseSort(q.Get("sort"))
+	if err != nil {
+		problem.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var page *models.PersonPage
+	if q.Has("cursor") {
+		var cursor *repository.Cursor
+		if raw := q.Get("cursor"); raw != "" {
+			cursor, err = repository.DecodeCursor(raw)
+			if err != nil {
+				problem.Error(w, r, "Invalid cursor", http.StatusBadRequest)
+				return
+			}
 		}
+		page, err = h.service.ListPage(r.Context(), limit, cursor, filters, sort)
+	} else {
+		var people []models.Person
+		people, err = h.service.List(r.Context(), limit, offset, filters, sort)
+		page = &models.PersonPage{Items: people, Offset: &offset}
+	}
+	if err != nil {
+		h.log(r).Error("Failed to list persons", zap.Error(err))
+		h.writeError(w, r, err, "Failed to list persons")
+		return
+	}
+	page.Limit = limit
+
+	if countMode != "none" {
+		total, estimated, err := h.service.Count(r.Context(), filters, countMode == "estimated")
+		if err != nil {
+			h.log(r).Error("Failed to count persons", zap.Error(err))
+			h.writeError(w, r, err, "Failed to count persons")
+			return
+		}
+		page.Total, page.TotalEstimated = &total, estimated
+	}
+
+	writePage(w, r, page, q.Get("envelope") == "true" || q.Has("cursor"))
+}
+
+// Search godoc
+// @Summary Поиск людей по ФИО
+// @Description Нечеткий поиск по имени, фамилии и отчеству с учетом опечаток.
+// @Description Результаты отсортированы по релевантности, совпавшие части полей выделены в highlights тегом <em>.
+// @Tags persons
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param limit query int false "Лимит"
+// @Success 200 {array} models.PersonSearchResult
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /persons/search [get]
+func (h *PersonHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		problem.Error(w, r, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
 	}
 
-	people, err := h.service.List(r.Context(), limit, offset, filters)
+	results, err := h.service.Search(r.Context(), query, limit)
 	if err != nil {
-		h.logger.Error("Failed to list persons", zap.Error(err))
-		http.Error(w, "Failed to list persons", http.StatusInternalServerError)
+		h.log(r).Error("Failed to search persons", zap.String("q", query), zap.Error(err))
+		h.writeError(w, r, err, "Failed to search persons")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(people)
+	json.NewEncoder(w).Encode(results)
 }
 
 // GetById godoc
@@ -104,9 +384,16 @@ func (h *PersonHandler) List(w http.ResponseWriter, r *http.Request) {
 // @Tags persons
 // @Produce json
 // @Param id path string true "UUID человека"
+// @Param include_deleted query bool false "Вернуть удаленного человека (только администратор)"
+// @Param as_of query string false "Состояние на момент времени (RFC 3339)"
 // @Success 200 {object} models.Person
-// @Failure 404 {object} string
-// @Failure 500 {object} string
+// @Header 200 {string} ETag "Версия записи для If-Match"
+// @Header 308 {string} Location "Адрес выжившего, если человек поглощен при слиянии"
+// @Failure 400 {object} problem.Problem
+// @Failure 403 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security AdminToken
 // @Router /persons/{id} [get]
 
 func (h *PersonHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -114,82 +401,275 @@ func (h *PersonHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		h.logger.Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	includeDeleted := q.Get("include_deleted") == "true"
+	if !h.requireAdmin(w, r, includeDeleted) {
 		return
 	}
 
-	person, err := h.service.GetByID(r.Context(), id)
+	var person *models.Person
+	if asOf := q.Get("as_of"); asOf != "" {
+		at, parseErr := time.Parse(time.RFC3339, asOf)
+		if parseErr != nil {
+			problem.Error(w, r, "Invalid as_of, expected RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		person, err = h.service.GetAsOf(r.Context(), id, at, includeDeleted)
+	} else {
+		person, err = h.service.GetByID(r.Context(), id, includeDeleted)
+	}
+	var merged *service.MergedError
+	if errors.As(err, &merged) {
+		target := "/persons/" + merged.Into.String()
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to get person by ID", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "Person not found", http.StatusNotFound)
+		h.log(r).Error("Failed to get person by ID", zap.String("id", idStr), zap.Error(err))
+		h.writeError(w, r, err, "Failed to get person")
 		return
 	}
 
+	setETag(w, person)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(person)
 }
 
 // Update godoc
-// @Summary Обновить данные человека по ID
+// @Summary Заменить данные человека по ID
+// @Description Полная замена: отсутствующее отчество очищается
 // @Tags persons
 // @Accept json
 // @Produce json
 // @Param id path string true "UUID человека"
+// @Param If-Match header string false "ETag из GET /persons/{id}"
 // @Param input body models.CreatePersonRequest true "Новые данные"
-// @Success 204 {object} models.UpdatePersonRequest
-// @Failure 400,404,500 {object} string
+// @Success 200 {object} models.Person
+// @Failure 400,404,409,412,413,422,428,500 {object} problem.Problem
 // @Router /persons/{id} [put]
 func (h *PersonHandler) Update(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		h.logger.Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
 		return
 	}
 
-	var req models.UpdatePersonRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode update request", zap.Error(err))
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
 		return
 	}
 
-	if err := h.service.Update(r.Context(), id, req); err != nil {
-		h.logger.Error("Failed to update person", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "Failed to update person", http.StatusInternalServerError)
+	var req models.CreatePersonRequest
+	if err := decodeBody(w, r, &req, maxBodyBytes); err != nil {
+		h.log(r).Error("Failed to decode update request", zap.Error(err))
+		writeDecodeError(w, r, err, "")
 		return
 	}
 
-	h.logger.Info("Updated person", zap.String("id", idStr))
-	w.WriteHeader(http.StatusNoContent)
+	person, err := h.service.Replace(r.Context(), id, version, req)
+	if err != nil {
+		h.log(r).Error("Failed to update person", zap.String("id", idStr), zap.Error(err))
+		h.writeError(w, r, err, "Failed to update person")
+		return
+	}
+
+	h.log(r).Info("Updated person", zap.String("id", idStr))
+	setETag(w, person)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
+}
+
+// Patch godoc
+// @Summary Частично обновить данные человека по ID
+// @Description application/merge-patch+json (RFC 7396) или application/json-patch+json (RFC 6902).
+// @Description Патч применяется к документу {"name", "surname", "patronymic"}.
+// @Tags persons
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "UUID человека"
+// @Param If-Match header string false "ETag из GET /persons/{id}"
+// @Param input body models.UpdatePersonRequest true "Merge Patch или массив операций JSON Patch"
+// @Success 200 {object} models.Person
+// @Failure 400,404,409,412,413,415,422,428,500 {object} problem.Problem
+// @Router /persons/{id} [patch]
+func (h *PersonHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json", "application/json":
+		apply = jsonpatch.MergePatch
+	case "application/json-patch+json":
+		apply = jsonpatch.Apply
+	default:
+		problem.Error(w, r, "Unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := readBody(w, r, maxBodyBytes)
+	if err != nil {
+		h.log(r).Error("Failed to read patch request", zap.Error(err))
+		writeDecodeError(w, r, err, "")
+		return
+	}
+
+	person, err := h.service.Patch(r.Context(), id, version, func(doc []byte) ([]byte, error) {
+		return apply(doc, patch)
+	})
+	if err != nil {
+		h.log(r).Error("Failed to patch person", zap.String("id", idStr), zap.Error(err))
+		h.writeError(w, r, err, "Failed to update person")
+		return
+	}
+
+	h.log(r).Info("Patched person", zap.String("id", idStr))
+	setETag(w, person)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
 }
 
 // Delete godoc
 // @Summary Удалить человека по ID
+// @Description Запись помечается удаленной и окончательно удаляется после срока хранения
 // @Tags persons
 // @Param id path string true "UUID человека"
+// @Param If-Match header string false "ETag из GET /persons/{id}"
 // @Success 204
-// @Failure 404,500 {object} string
+// @Failure 400,404,412,428,500 {object} problem.Problem
 // @Router /persons/{id} [delete]
 func (h *PersonHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		h.logger.Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
 		return
 	}
 
-	if err := h.service.Delete(r.Context(), id); err != nil {
-		h.logger.Error("Failed to delete person", zap.String("id", idStr), zap.Error(err))
-		http.Error(w, "Failed to delete person", http.StatusInternalServerError)
+	if err := h.service.Delete(r.Context(), id, version); err != nil {
+		h.log(r).Error("Failed to delete person", zap.String("id", idStr), zap.Error(err))
+		h.writeError(w, r, err, "Failed to delete person")
 		return
 	}
 
-	h.logger.Info("Deleted person", zap.String("id", idStr))
+	h.log(r).Info("Deleted person", zap.String("id", idStr))
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Restore godoc
+// @Summary Восстановить удаленного человека по ID
+// @Tags persons
+// @Produce json
+// @Param id path string true "UUID человека"
+// @Success 200 {object} models.Person
+// @Failure 400,404,409,500 {object} problem.Problem
+// @Router /persons/{id}/restore [post]
+func (h *PersonHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	person, err := h.service.Restore(r.Context(), id)
+	if errors.Is(err, repository.ErrNotDeleted) {
+		problem.Error(w, r, "Person is not deleted", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.log(r).Error("Failed to restore person", zap.String("id", idStr), zap.Error(err))
+		h.writeError(w, r, err, "Failed to restore person")
+		return
+	}
+
+	h.log(r).Info("Restored person", zap.String("id", idStr))
+	setETag(w, person)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
+}
+
+// History godoc
+// @Summary История изменений человека
+// @Description Снимки до и после каждого изменения, от новых к старым.
+// @Description changed_by - справочное значение из заголовка X-Actor, оно не проверяется и не подтверждает авторство.
+// @Description История удаленного человека отдается только администратору с include_deleted=true.
+// @Tags persons
+// @Produce json
+// @Param id path string true "UUID человека"
+// @Param limit query int false "Лимит, не больше 100" default(10)
+// @Param offset query int false "Смещение"
+// @Param include_deleted query bool false "История удаленного человека (только администратор)"
+// @Success 200 {array} models.PersonHistoryEntry
+// @Failure 400,403,404,500 {object} problem.Problem
+// @Security AdminToken
+// @Router /persons/{id}/history [get]
+func (h *PersonHandler) History(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.log(r).Error("Invalid UUID", zap.String("id", idStr), zap.Error(err))
+		problem.Error(w, r, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	includeDeleted := q.Get("include_deleted") == "true"
+	if !h.requireAdmin(w, r, includeDeleted) {
+		return
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	limit = min(limit, 100)
+	offset, err := strconv.Atoi(q.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	entries, err := h.service.History(r.Context(), id, limit, offset, includeDeleted)
+	if err != nil {
+		h.log(r).Error("Failed to get person history", zap.String("id", idStr), zap.Error(err))
+		h.writeError(w, r, err, "Failed to get person history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
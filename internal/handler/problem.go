@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"effective-mobile-task/internal/problem"
+	"effective-mobile-task/internal/service"
+)
+
+// fieldErrors достает ошибки полей из *service.ValidationError.
+func fieldErrors(err error) []problem.FieldError {
+	var verr *service.ValidationError
+	if !errors.As(err, &verr) {
+		return nil
+	}
+	fields := make([]problem.FieldError, len(verr.Fields))
+	for i, f := range verr.Fields {
+		fields[i] = problem.FieldError{Field: f.Field, Message: f.Message}
+	}
+	return fields
+}
+
+// invalidBody отвечает 400 на тело, которое не разбирается как JSON. Поле с неверным типом
+// попадает в errors; prefix - путь элемента, например items[3].
+func invalidBody(w http.ResponseWriter, r *http.Request, err error, prefix string) {
+	p := problem.Problem{Type: problem.TypeInvalidRequest, Status: http.StatusBadRequest}
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		p.Detail = "Invalid request body: wrong field type"
+		p.Errors = []problem.FieldError{{Field: fieldPath(prefix, typeErr.Field), Message: "must be " + jsonType(typeErr.Type)}}
+	case errors.As(err, &typeErr):
+		p.Detail = fmt.Sprintf("Invalid request body: expected %s", jsonType(typeErr.Type))
+	case errors.As(err, &syntaxErr):
+		p.Detail = fmt.Sprintf("Invalid JSON at offset %d: %v", syntaxErr.Offset, syntaxErr)
+	case errors.Is(err, io.EOF):
+		p.Detail = "Request body is empty"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		p.Detail = "Request body is truncated"
+	case errors.Is(err, errTrailingData):
+		p.Detail = "Request body must contain a single JSON value"
+	default:
+		p.Detail = "Invalid request body"
+	}
+	if prefix != "" {
+		p.Detail = prefix + ": " + p.Detail
+	}
+	problem.Write(w, r, p)
+}
+
+// jsonType называет тип JSON, в который декодируется t.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "a " + t.String()
+}
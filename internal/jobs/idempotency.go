@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-task/internal/service"
+	"go.uber.org/zap"
+)
+
+// IdempotencyCleanupJob периодически удаляет истекшие ключи идемпотентности.
+type IdempotencyCleanupJob struct {
+	service  *service.IdempotencyService
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+func NewIdempotencyCleanupJob(s *service.IdempotencyService, logger *zap.Logger, interval time.Duration) *IdempotencyCleanupJob {
+	return &IdempotencyCleanupJob{service: s, logger: logger, interval: interval}
+}
+
+// Run работает до отмены ctx.
+func (j *IdempotencyCleanupJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := j.service.PurgeExpired(ctx)
+		if err != nil {
+			j.logger.Error("Failed to purge expired idempotency keys", zap.Error(err))
+		} else if n > 0 {
+			j.logger.Info("Purged expired idempotency keys", zap.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
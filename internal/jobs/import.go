@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-task/internal/service"
+	"go.uber.org/zap"
+)
+
+// ImportJob разбирает очередь импортов. Импорты, прерванные остановкой сервера,
+// продолжаются после перезапуска, когда истечет lease.
+type ImportJob struct {
+	service  *service.ImportService
+	logger   *zap.Logger
+	interval time.Duration
+	lease    time.Duration
+}
+
+func NewImportJob(s *service.ImportService, logger *zap.Logger, interval, lease time.Duration) *ImportJob {
+	return &ImportJob{service: s, logger: logger, interval: interval, lease: lease}
+}
+
+// Run работает до отмены ctx.
+func (j *ImportJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *ImportJob) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := j.service.ProcessNext(ctx, j.lease)
+		if err != nil && ctx.Err() == nil {
+			j.logger.Error("Failed to process import", zap.Error(err))
+		}
+		if !processed {
+			return
+		}
+	}
+}
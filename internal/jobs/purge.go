@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/service"
+	"go.uber.org/zap"
+)
+
+// PurgeJob периодически окончательно удаляет людей, удаленных раньше чем retention назад.
+type PurgeJob struct {
+	service   *service.PersonService
+	logger    *zap.Logger
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewPurgeJob(s *service.PersonService, logger *zap.Logger, interval, retention time.Duration) *PurgeJob {
+	return &PurgeJob{service: s, logger: logger, interval: interval, retention: retention}
+}
+
+// Run работает до отмены ctx.
+func (j *PurgeJob) Run(ctx context.Context) {
+	ctx = actor.WithName(ctx, "purge-job")
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *PurgeJob) purge(ctx context.Context) {
+	n, err := j.service.PurgeDeleted(ctx, j.retention)
+	if err != nil {
+		j.logger.Error("Failed to purge deleted persons", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		j.logger.Info("Purged deleted persons", zap.Int64("count", n))
+	}
+}
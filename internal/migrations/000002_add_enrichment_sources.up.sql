@@ -0,0 +1 @@
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS enrichment_sources JSONB;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_persons_birth_year;
+ALTER TABLE persons DROP COLUMN IF EXISTS birth_year;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS birth_year INT;
+
+UPDATE persons
+SET birth_year = EXTRACT(YEAR FROM created_at)::INT - age
+WHERE birth_year IS NULL AND age > 0;
+
+CREATE INDEX IF NOT EXISTS idx_persons_birth_year ON persons(birth_year);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_persons_deleted_at;
+ALTER TABLE persons DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_persons_deleted_at ON persons(deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
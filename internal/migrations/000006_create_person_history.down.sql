@@ -0,0 +1 @@
+DROP TABLE IF EXISTS person_history;
\ No newline at end of file
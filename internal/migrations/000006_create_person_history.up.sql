@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS person_history (
+    id BIGSERIAL PRIMARY KEY,
+    person_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(150) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    before JSONB,
+    after JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_history_person_id ON person_history(person_id, changed_at DESC, id DESC);
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_persons_created_at_id ON persons(created_at DESC, id DESC);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_persons_patronymic_trgm;
+DROP INDEX IF EXISTS idx_persons_surname_trgm;
+DROP INDEX IF EXISTS idx_persons_name_trgm;
+DROP INDEX IF EXISTS idx_persons_search_vector;
+ALTER TABLE persons DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || surname || ' ' || COALESCE(patronymic, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_persons_search_vector ON persons USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_persons_name_trgm ON persons USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_persons_surname_trgm ON persons USING GIN (surname gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_persons_patronymic_trgm ON persons USING GIN (patronymic gin_trgm_ops);
\ No newline at end of file
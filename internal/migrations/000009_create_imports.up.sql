@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS imports (
+    id UUID PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    mapping JSONB NOT NULL,
+    delimiter VARCHAR(1) NOT NULL DEFAULT ',',
+    columns TEXT[] NOT NULL,
+    data BYTEA NOT NULL,
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    created_rows INT NOT NULL DEFAULT 0,
+    failed_rows INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT,
+    created_by VARCHAR(150) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    heartbeat_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_imports_unfinished ON imports(created_at) WHERE status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS import_errors (
+    import_id UUID NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    record TEXT[] NOT NULL,
+    error TEXT NOT NULL,
+    PRIMARY KEY (import_id, row_number)
+);
\ No newline at end of file
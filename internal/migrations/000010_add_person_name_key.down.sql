@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_persons_name_key_trgm;
+DROP INDEX IF EXISTS idx_persons_name_key;
+ALTER TABLE persons DROP COLUMN IF EXISTS name_key;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- name_key - ФИО без учета регистра и лишних пробелов, как repository.NameKey.
+-- Индекс не уникальный: запрет одинаковых ФИО включается отдельной миграцией
+-- из optional (scripts/migrate.go -unique-names) вместе с UNIQUE_PERSON_NAMES.
+ALTER TABLE persons ADD COLUMN IF NOT EXISTS name_key TEXT
+    GENERATED ALWAYS AS (
+        lower(regexp_replace(btrim(name), '\s+', ' ', 'g')) || '|' ||
+        lower(regexp_replace(btrim(surname), '\s+', ' ', 'g')) || '|' ||
+        lower(regexp_replace(btrim(COALESCE(patronymic, '')), '\s+', ' ', 'g'))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_persons_name_key ON persons(name_key) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_persons_name_key_trgm ON persons USING GIN (name_key gin_trgm_ops);
\ No newline at end of file
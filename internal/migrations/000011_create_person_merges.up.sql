@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS person_merges (
+    merged_id UUID PRIMARY KEY,
+    survivor_id UUID NOT NULL,
+    merged_by VARCHAR(150) NOT NULL,
+    merged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_merges_survivor_id ON person_merges(survivor_id);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE persons
+    DROP COLUMN IF EXISTS patronymic_original,
+    DROP COLUMN IF EXISTS surname_original,
+    DROP COLUMN IF EXISTS name_original;
\ No newline at end of file
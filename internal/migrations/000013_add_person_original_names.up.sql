@@ -0,0 +1,9 @@
+-- name, surname и patronymic хранят канонический вид ФИО (service.CanonicalName и CanonicalSurname), *_original - присланный клиентом.
+ALTER TABLE persons
+    ADD COLUMN IF NOT EXISTS name_original TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS surname_original TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS patronymic_original TEXT NOT NULL DEFAULT '';
+
+-- ФИО существующих записей приводит к каноническому виду scripts/migrate.go после миграций:
+-- правила регистра с частицами есть только в Go.
+UPDATE persons SET name_original = name, surname_original = surname, patronymic_original = COALESCE(patronymic, '');
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_persons_name_key_unique;
\ No newline at end of file
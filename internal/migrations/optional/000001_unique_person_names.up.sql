@@ -0,0 +1,3 @@
+-- Запрещает двух неудаленных людей с одинаковым ФИО (name_key); включается вместе с UNIQUE_PERSON_NAMES=true.
+-- Существующие дубликаты нужно объединить до применения миграции.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_persons_name_key_unique ON persons(name_key) WHERE deleted_at IS NULL;
\ No newline at end of file
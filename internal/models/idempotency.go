@@ -0,0 +1,8 @@
+package models
+
+// IdempotentResponse - сохраненный ответ на запрос с Idempotency-Key, который отдается при повторах.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     map[string]string
+	Body       []byte
+}
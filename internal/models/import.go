@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Статусы импорта.
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	// ImportFailed - файл не удалось разобрать; при временных сбоях импорт остается running
+	ImportFailed = "failed"
+)
+
+// Форматы загружаемых файлов.
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+// Import
+type Import struct {
+	ID     uuid.UUID `json:"id" example:"6f1c2b1e-8a53-4a5e-9d8c-2a7f3c1e9b10"`
+	Status string    `json:"status" example:"running"`
+	Format string    `json:"format" example:"csv"`
+	// Mapping - поле человека (name, surname, patronymic) -> столбец CSV или ключ NDJSON
+	Mapping   map[string]string `json:"mapping"`
+	Delimiter string            `json:"delimiter,omitempty" example:";"`
+	// Columns - заголовок CSV; для NDJSON - единственный столбец record с исходной строкой
+	Columns       []string   `json:"columns" example:"Фамилия,Имя,Отчество"`
+	TotalRows     int        `json:"total_rows" example:"1200"`
+	ProcessedRows int        `json:"processed_rows" example:"300"`
+	CreatedRows   int        `json:"created_rows" example:"295"`
+	FailedRows    int        `json:"failed_rows" example:"5"`
+	Attempts      int        `json:"attempts" example:"1"`
+	Error         string     `json:"error,omitempty"`
+	CreatedBy     string     `json:"created_by" example:"hr@example.com"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+
+	// Data - исходный файл, по нему импорт продолжается после перезапуска
+	Data []byte `json:"-"`
+}
+
+// ImportError - строка файла, которую не удалось импортировать.
+type ImportError struct {
+	Row    int
+	Record []string
+	Error  string
+}
@@ -8,27 +8,146 @@ import (
 
 // Person
 type Person struct {
-	ID          uuid.UUID `json:"id" example:"1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"`
-	Name        string    `json:"name" example:"Dmitriy"`
-	Surname     string    `json:"surname" example:"Ushakov"`
-	Patronymic  string    `json:"patronymic,omitempty" example:"Vasilevich"`
-	Age         int       `json:"age" example:"30"`
-	Gender      string    `json:"gender" example:"male"`
-	Nationality string    `json:"nationality" example:"RU"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id" example:"1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"`
+	Name        string     `json:"name" example:"Dmitriy"`
+	Surname     string     `json:"surname" example:"Ushakov"`
+	Patronymic  string     `json:"patronymic,omitempty" example:"Vasilevich"`
+	Age         int        `json:"age" example:"30"`
+	BirthYear   int        `json:"birth_year,omitempty" example:"1995"`
+	Gender      string     `json:"gender" example:"male"`
+	Nationality string     `json:"nationality" example:"RU"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Version     int        `json:"version" example:"1"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+
+	// ФИО в том виде, в каком его прислал клиент; Name, Surname и Patronymic - канонический вид.
+	NameOriginal       string `json:"name_original,omitempty" example:" DMITRIY "`
+	SurnameOriginal    string `json:"surname_original,omitempty" example:"ushakov"`
+	PatronymicOriginal string `json:"patronymic_original,omitempty" example:"Vasilevich"`
+
+	EnrichmentSources []EnrichmentSource `json:"enrichment_sources,omitempty"`
+}
+
+// EnrichmentSource
+type EnrichmentSource struct {
+	Provider    string  `json:"provider" example:"http"`
+	Attribute   string  `json:"attribute" example:"gender"`
+	Value       string  `json:"value,omitempty" example:"male"`
+	Probability float64 `json:"probability,omitempty" example:"0.98"`
+	Weight      float64 `json:"weight" example:"1"`
+	Selected    bool    `json:"selected"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// CurrentAge возвращает возраст на момент now по оценочному году рождения.
+// Для записей без года рождения возвращается сохраненный возраст.
+func (p *Person) CurrentAge(now time.Time) int {
+	if p.BirthYear == 0 {
+		return p.Age
+	}
+	return now.Year() - p.BirthYear
 }
 
 // CreatePersonRequest
+// Ограничения длины совпадают с размерами колонок persons.
 type CreatePersonRequest struct {
-	Name       string `json:"name" example:"Dmitriy"`
-	Surname    string `json:"surname" example:"Ushakov"`
-	Patronymic string `json:"patronymic,omitempty" example:"Vasilevich"`
+	Name       string `json:"name" example:"Dmitriy" validate:"trim,required,max=150,name" maxLength:"150"`
+	Surname    string `json:"surname" example:"Ushakov" validate:"trim,required,max=150,name" maxLength:"150"`
+	Patronymic string `json:"patronymic,omitempty" example:"Vasilevich" validate:"trim,max=150,name" maxLength:"150"`
 }
 
 // UpdatePersonRequest
 type UpdatePersonRequest struct {
-	Name       *string `json:"name,omitempty"`
-	Surname    *string `json:"surname,omitempty"`
-	Patronymic *string `json:"patronymic,omitempty"`
+	Name       *string `json:"name,omitempty" validate:"trim,required,max=150,name" maxLength:"150"`
+	Surname    *string `json:"surname,omitempty" validate:"trim,required,max=150,name" maxLength:"150"`
+	Patronymic *string `json:"patronymic,omitempty" validate:"trim,max=150,name" maxLength:"150"`
+
+	// Исходные значения до приведения к каноническому виду; заполняет сервис.
+	NameOriginal       *string `json:"-" swaggerignore:"true"`
+	SurnameOriginal    *string `json:"-" swaggerignore:"true"`
+	PatronymicOriginal *string `json:"-" swaggerignore:"true"`
+}
+
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryPurge   = "purge"
+	HistoryMerge   = "merge"
+)
+
+// PersonHistoryEntry
+type PersonHistoryEntry struct {
+	ID       int64     `json:"id" example:"42"`
+	PersonID uuid.UUID `json:"person_id" example:"1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"`
+	Action   string    `json:"action" example:"update"`
+	// ChangedBy - значение заголовка X-Actor, переданное клиентом; сервер его не проверяет,
+	// поэтому поле справочное и не подтверждает авторство
+	ChangedBy string    `json:"changed_by" example:"operator@example.com"`
+	ChangedAt time.Time `json:"changed_at"`
+	Before    *Person   `json:"before,omitempty"`
+	After     *Person   `json:"after,omitempty"`
+}
+
+// PersonPage
+type PersonPage struct {
+	Items          []Person `json:"items"`
+	Total          *int64   `json:"total,omitempty" example:"120"`
+	TotalEstimated bool     `json:"total_estimated,omitempty"`
+	Limit          int      `json:"limit" example:"10"`
+	Offset         *int     `json:"offset,omitempty" example:"20"`
+	NextCursor     string   `json:"next_cursor,omitempty" example:"eyJjIjoiMjAyNS0wMS0wMVQwMDowMDowMFoiLCJpIjoiLi4uIn0"`
+	PrevCursor     string   `json:"prev_cursor,omitempty"`
+	Next           string   `json:"next,omitempty" example:"/persons?limit=10&offset=30"`
+	Prev           string   `json:"prev,omitempty" example:"/persons?limit=10&offset=10"`
+}
+
+// PersonSearchResult
+type PersonSearchResult struct {
+	Person
+	Score float64 `json:"score" example:"0.83"`
+	// Highlights - поля с совпавшими частями в <em>
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// Статусы элементов массового создания.
+const (
+	BulkCreated         = "created"
+	BulkDuplicate       = "duplicate"
+	BulkValidationError = "validation_error"
+	BulkEnrichmentError = "enrichment_error"
+	// BulkSkipped - элемент корректен, но не сохранен, потому что в режиме atomic упал другой элемент.
+	BulkSkipped = "skipped"
+)
+
+// BulkItemResult
+type BulkItemResult struct {
+	Index  int     `json:"index" example:"0"`
+	Status string  `json:"status" example:"created"`
+	Person *Person `json:"person,omitempty"`
+	// DuplicateOf - индекс элемента запроса с тем же ФИО
+	DuplicateOf *int `json:"duplicate_of,omitempty" example:"0"`
+	// ExistingID - id уже сохраненного человека с тем же ФИО
+	ExistingID *uuid.UUID `json:"existing_id,omitempty" example:"1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// DuplicateCluster - группа людей, похожих друг на друга по ФИО.
+type DuplicateCluster struct {
+	// Score - средняя оценка пар группы: сходство ФИО и совпадение атрибутов, от 0 до 1
+	Score float64 `json:"score" example:"0.82"`
+	// MatchingAttributes - атрибуты, совпадающие у всех людей группы
+	MatchingAttributes []string `json:"matching_attributes" example:"gender,nationality"`
+	Persons            []Person `json:"persons"`
+}
+
+// MergePersonsRequest
+type MergePersonsRequest struct {
+	SurvivorID uuid.UUID   `json:"survivor_id" example:"1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"`
+	MergedIDs  []uuid.UUID `json:"merged_ids"`
+	// Fields - откуда брать значение поля (name, surname, patronymic, age, gender, nationality):
+	// survivor (по умолчанию, пустое дополняется из остальных), newest, oldest, most_common или id одной из записей
+	Fields map[string]string `json:"fields,omitempty"`
 }
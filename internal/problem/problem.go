@@ -0,0 +1,100 @@
+// Package problem пишет ошибки API в формате application/problem+json (RFC 7807).
+//
+// Клиенты различают ошибки по type, а не по тексту title и detail. Для ошибок, которые
+// не требуют особой обработки, type - about:blank, а title - стандартный текст статуса.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"effective-mobile-task/internal/requestid"
+)
+
+const ContentType = "application/problem+json"
+
+// Типы проблем.
+const (
+	TypeBlank                = "about:blank"
+	TypeInvalidRequest       = "/problems/invalid-request"
+	TypeValidation           = "/problems/validation-error"
+	TypeNotFound             = "/problems/not-found"
+	TypeConflict             = "/problems/conflict"
+	TypeDuplicate            = "/problems/duplicate"
+	TypeVersionMismatch      = "/problems/version-mismatch"
+	TypePreconditionRequired = "/problems/precondition-required"
+	TypeKeyInProgress        = "/problems/idempotency-key-in-progress"
+	TypeKeyReused            = "/problems/idempotency-key-reused"
+)
+
+var titles = map[string]string{
+	TypeInvalidRequest:       "Invalid request",
+	TypeValidation:           "Validation failed",
+	TypeNotFound:             "Resource not found",
+	TypeConflict:             "Conflict",
+	TypeDuplicate:            "Person already exists",
+	TypeVersionMismatch:      "Resource was modified",
+	TypePreconditionRequired: "Precondition required",
+	TypeKeyInProgress:        "Request is in progress",
+	TypeKeyReused:            "Idempotency key reused",
+}
+
+// statusTypes - тип по умолчанию для статуса в Error.
+var statusTypes = map[int]string{
+	http.StatusBadRequest:           TypeInvalidRequest,
+	http.StatusNotFound:             TypeNotFound,
+	http.StatusConflict:             TypeConflict,
+	http.StatusPreconditionFailed:   TypeVersionMismatch,
+	http.StatusUnprocessableEntity:  TypeValidation,
+	http.StatusPreconditionRequired: TypePreconditionRequired,
+}
+
+// Problem
+type Problem struct {
+	Type   string `json:"type" example:"/problems/validation-error"`
+	Title  string `json:"title" example:"Validation failed"`
+	Status int    `json:"status" example:"422"`
+	Detail string `json:"detail,omitempty" example:"validation failed: name is required"`
+	// Instance - путь запроса
+	Instance string `json:"instance,omitempty" example:"/persons"`
+	// RequestID - совпадает с заголовком X-Request-ID ответа
+	RequestID string `json:"request_id,omitempty" example:"5f0c6a8e-2d7b-4f0e-9a41-0b8f6c7e1d23"`
+	// Errors - ошибки отдельных полей тела или параметров запроса
+	Errors []FieldError `json:"errors,omitempty"`
+	// ExistingID - id уже существующего человека для type /problems/duplicate
+	ExistingID string `json:"existing_id,omitempty" example:"1e8c72e6-3c77-4b9b-b44d-1b0e44c3c0b9"`
+}
+
+// FieldError
+type FieldError struct {
+	// Field - поле тела (name, items[3].surname) или параметр запроса (limit)
+	Field   string `json:"field" example:"name"`
+	Message string `json:"message" example:"is required"`
+}
+
+// Write отвечает проблемой p, заполняя пустые type и title, путь и идентификатор запроса.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = TypeBlank
+	}
+	if p.Title == "" {
+		p.Title = titles[p.Type]
+		if p.Title == "" {
+			p.Title = http.StatusText(p.Status)
+		}
+	}
+	p.Instance = r.URL.Path
+	p.RequestID = requestid.FromContext(r.Context())
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error - замена http.Error: проблема со статусом status, типом по умолчанию для него и detail.
+func Error(w http.ResponseWriter, r *http.Request, detail string, status int, errs ...FieldError) {
+	Write(w, r, Problem{Type: statusTypes[status], Status: status, Detail: detail, Errors: errs})
+}
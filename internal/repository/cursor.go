@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"effective-mobile-task/internal/models"
+)
+
+// Cursor - граница страницы при keyset-пагинации: значения ключей сортировки записи (включая id).
+type Cursor struct {
+	// Sort - порядок, в котором выдан курсор; с другим порядком курсор не принимается.
+	Sort string   `json:"s"`
+	Key  []string `json:"k"`
+	// Backward - нужна страница перед курсором, а не после него.
+	Backward bool `json:"b,omitempty"`
+}
+
+// Page - страница списка и курсоры соседних страниц (nil, если соседней страницы нет).
+type Page struct {
+	Items []models.Person
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// Encode возвращает непрозрачное для клиента представление курсора.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return &c, nil
+}
+
+// cursorValues проверяет, что курсор выдан для порядка s, и возвращает типизированные значения ключей.
+func (s Sort) cursorValues(c *Cursor) ([]interface{}, error) {
+	if c.Sort != s.String() {
+		return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidCursor, c.Sort)
+	}
+	keys := s.keys()
+	if len(c.Key) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		v, err := parseSortValue(k.kind, c.Key[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (s Sort) cursorOf(p models.Person, backward bool) *Cursor {
+	keys := s.keys()
+	c := &Cursor{Sort: s.String(), Backward: backward, Key: make([]string, len(keys))}
+	for i, v := range s.values(p) {
+		c.Key[i] = formatSortValue(keys[i].kind, v)
+	}
+	return c
+}
+
+// newPage собирает страницу из limit+1 записей, выбранных в направлении курсора:
+// лишняя запись означает, что в этом направлении есть еще страница.
+func newPage(people []models.Person, limit int, sort Sort, cursor *Cursor) *Page {
+	more := len(people) > limit
+	if more {
+		people = people[:limit]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(people)-1; i < j; i, j = i+1, j-1 {
+			people[i], people[j] = people[j], people[i]
+		}
+	}
+
+	page := &Page{Items: people}
+	if len(people) == 0 {
+		return page
+	}
+	first, last := people[0], people[len(people)-1]
+	if backward {
+		page.Next = sort.cursorOf(last, false)
+		if more {
+			page.Prev = sort.cursorOf(first, true)
+		}
+	} else {
+		if more {
+			page.Next = sort.cursorOf(last, false)
+		}
+		if cursor != nil {
+			page.Prev = sort.cursorOf(first, true)
+		}
+	}
+	return page
+}
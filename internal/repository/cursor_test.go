@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	c := Cursor{Sort: "-created_at", Key: []string{"2025-01-01T00:00:00Z", uuid.NewString()}, Backward: true}
+
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if !reflect.DeepEqual(*got, c) {
+		t.Errorf("DecodeCursor = %+v, want %+v", *got, c)
+	}
+
+	for _, raw := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := DecodeCursor(raw); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want %v", raw, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestCursorValues(t *testing.T) {
+	s := Sort{{Field: "age"}, {Field: "created_at", Desc: true}}
+	at := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	p := models.Person{ID: uuid.New(), BirthYear: 1990, CreatedAt: at}
+
+	values, err := s.cursorValues(s.cursorOf(p, false))
+	if err != nil {
+		t.Fatalf("cursorValues: %v", err)
+	}
+	if want := []interface{}{1990, at, p.ID.String()}; !reflect.DeepEqual(values, want) {
+		t.Errorf("cursorValues = %v, want %v", values, want)
+	}
+
+	tests := []struct {
+		name   string
+		cursor *Cursor
+	}{
+		{"other sort", &Cursor{Sort: "name", Key: []string{"a", "b"}}},
+		{"wrong key count", &Cursor{Sort: s.String(), Key: []string{"1990"}}},
+		{"bad int", &Cursor{Sort: s.String(), Key: []string{"x", at.Format(time.RFC3339Nano), "id"}}},
+		{"bad time", &Cursor{Sort: s.String(), Key: []string{"1990", "yesterday", "id"}}},
+	}
+	for _, tt := range tests {
+		if _, err := s.cursorValues(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestGetPageWalksBothDirections(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryPersonRepository(false)
+	var want []string
+	for i := 0; i < 7; i++ {
+		p := models.Person{ID: uuid.New(), Name: fmt.Sprintf("Name%d", i), Surname: "Test"}
+		if err := repo.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, p.Name)
+	}
+	sort := Sort{{Field: "name"}}
+
+	var forward []string
+	var pages []*Page
+	var cursor *Cursor
+	for {
+		page, err := repo.GetPage(ctx, 3, cursor, filter.Filter{}, sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		for _, p := range page.Items {
+			forward = append(forward, p.Name)
+		}
+		if page.Next == nil {
+			break
+		}
+		cursor = page.Next
+	}
+	if !reflect.DeepEqual(forward, want) {
+		t.Fatalf("forward = %v, want %v", forward, want)
+	}
+	if len(pages) != 3 || pages[0].Prev != nil {
+		t.Fatalf("got %d pages, first prev %v", len(pages), pages[0].Prev)
+	}
+
+	// с последней страницы назад получаем те же страницы
+	back, err := repo.GetPage(ctx, 3, pages[2].Prev, filter.Filter{}, sort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back.Items, pages[1].Items) {
+		t.Errorf("backward page = %v, want %v", back.Items, pages[1].Items)
+	}
+	if back.Prev == nil || back.Next == nil {
+		t.Errorf("middle page must have both cursors")
+	}
+
+	if _, err := repo.GetPage(ctx, 3, pages[1].Next, filter.Filter{}, Sort{{Field: "surname"}}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor for another sort: error = %v, want %v", err, ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrConstraint = errors.New("constraint violation")
+	// ErrVersionMismatch - запись изменилась после того, как клиент ее прочитал.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrInvalidCursor - курсор пагинации поврежден или выдан не этим сервисом.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort - сортировка по неизвестному или повторяющемуся полю.
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrNotDeleted - восстановление записи, которая не удалена; частный случай ErrConflict.
+	ErrNotDeleted = fmt.Errorf("%w: person is not deleted", ErrConflict)
+	// ErrKeyInProgress - запрос с тем же Idempotency-Key еще выполняется; частный случай ErrConflict.
+	ErrKeyInProgress = fmt.Errorf("%w: request with the same idempotency key is in progress", ErrConflict)
+	// ErrKeyMismatch - Idempotency-Key уже использован для другого запроса.
+	ErrKeyMismatch = errors.New("idempotency key was used for a different request")
+)
+
+// mapError приводит ошибки database/sql и pq к ошибкам пакета, сохраняя исходную ошибку в цепочке.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Name() == "unique_violation":
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case pqErr.Code.Class() == "23", pqErr.Code.Name() == "string_data_right_truncation":
+			return fmt.Errorf("%w: %w", ErrConstraint, err)
+		}
+	}
+	return err
+}
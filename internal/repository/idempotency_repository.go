@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"effective-mobile-task/internal/models"
+)
+
+type IdempotencyRepository struct {
+	db DBTX
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) AcquireKey(ctx context.Context, scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*models.IdempotentResponse, error) {
+	// новая строка или захват истекшей либо брошенной одним запросом, чтобы два параллельных
+	// запроса не могли занять ключ одновременно
+	var acquired bool
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys AS k (scope, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		ON CONFLICT (scope, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, response_headers = NULL, response_body = NULL,
+			locked_at = NOW(), created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE k.expires_at < NOW()
+			OR (k.status_code IS NULL AND k.fingerprint = EXCLUDED.fingerprint AND k.locked_at < NOW() - make_interval(secs => $5))
+		RETURNING true`,
+		scope, key, fingerprint, ttl.Seconds(), lockTimeout.Seconds()).Scan(&acquired)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, mapError(err)
+	}
+
+	var storedFingerprint string
+	var status sql.NullInt64
+	var header []byte
+	resp := &models.IdempotentResponse{}
+	err = r.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, response_headers, response_body FROM idempotency_keys
+		WHERE scope = $1 AND key = $2`, scope, key).Scan(&storedFingerprint, &status, &header, &resp.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		// ключ удалили между запросами: пусть клиент повторит
+		return nil, ErrKeyInProgress
+	}
+	if err != nil {
+		return nil, mapError(err)
+	}
+	switch {
+	case storedFingerprint != fingerprint:
+		return nil, ErrKeyMismatch
+	case !status.Valid:
+		return nil, ErrKeyInProgress
+	}
+	resp.StatusCode = int(status.Int64)
+	if err := json.Unmarshal(header, &resp.Header); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *IdempotencyRepository) SaveResponse(ctx context.Context, scope, key string, resp models.IdempotentResponse) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, response_headers = $4, response_body = $5
+		WHERE scope = $1 AND key = $2`, scope, key, resp.StatusCode, header, resp.Body)
+	return mapError(err)
+}
+
+func (r *IdempotencyRepository) ReleaseKey(ctx context.Context, scope, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL`, scope, key)
+	return mapError(err)
+}
+
+func (r *IdempotencyRepository) PurgeExpiredKeys(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-task/internal/models"
+)
+
+// IdempotencyStore - ответы на запросы с Idempotency-Key. Реализации: IdempotencyRepository (Postgres)
+// и MemoryIdempotencyRepository. Ключ действует в пределах scope (метод, путь и автор запроса).
+type IdempotencyStore interface {
+	// AcquireKey занимает ключ под запрос с отпечатком fingerprint на ttl и возвращает nil, nil,
+	// если ключ свободен, истек или занят запросом, который не завершился за lockTimeout.
+	// Если запрос с этим ключом уже выполнен, возвращается его ответ. Ключ, занятый другим запросом,
+	// дает ErrKeyMismatch, а еще выполняющимся тем же запросом - ErrKeyInProgress.
+	AcquireKey(ctx context.Context, scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*models.IdempotentResponse, error)
+	SaveResponse(ctx context.Context, scope, key string, resp models.IdempotentResponse) error
+	// ReleaseKey освобождает ключ без ответа, чтобы повтор выполнил запрос заново.
+	ReleaseKey(ctx context.Context, scope, key string) error
+	PurgeExpiredKeys(ctx context.Context) (int64, error)
+}
+
+var (
+	_ IdempotencyStore = (*IdempotencyRepository)(nil)
+	_ IdempotencyStore = (*MemoryIdempotencyRepository)(nil)
+)
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const importColumns = `id, status, format, mapping, delimiter, columns, total_rows, processed_rows, created_rows, failed_rows, attempts, error, created_by, created_at, updated_at, finished_at`
+
+type ImportRepository struct {
+	db DBTX
+}
+
+func NewImportRepository(db *sql.DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+func (r *ImportRepository) CreateImport(ctx context.Context, imp models.Import) error {
+	mapping, err := json.Marshal(imp.Mapping)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO imports (id, status, format, mapping, delimiter, columns, data, total_rows, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		imp.ID, imp.Status, imp.Format, mapping, imp.Delimiter, pq.Array(imp.Columns), imp.Data, imp.TotalRows, imp.CreatedBy)
+	return mapError(err)
+}
+
+func (r *ImportRepository) GetImport(ctx context.Context, id uuid.UUID) (*models.Import, error) {
+	imp, err := scanImport(r.db.QueryRowContext(ctx, `SELECT `+importColumns+` FROM imports WHERE id = $1`, id))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return imp, nil
+}
+
+func (r *ImportRepository) ClaimImport(ctx context.Context, lease time.Duration) (*models.Import, error) {
+	// SKIP LOCKED позволяет нескольким экземплярам сервиса разбирать очередь, не мешая друг другу
+	var data []byte
+	imp, err := scanImport(scannerWith{r.db.QueryRowContext(ctx, `
+		UPDATE imports SET status = $1, attempts = attempts + 1, heartbeat_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM imports
+			WHERE status = $2 OR (status = $1 AND heartbeat_at < NOW() - make_interval(secs => $3))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+importColumns+`, data`,
+		models.ImportRunning, models.ImportPending, lease.Seconds()), &data})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	imp.Data = data
+	return imp, nil
+}
+
+func (r *ImportRepository) SaveProgress(ctx context.Context, imp *models.Import, errs []models.ImportError) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE imports SET processed_rows = $2, created_rows = $3, failed_rows = $4, heartbeat_at = NOW(), updated_at = NOW()
+			WHERE id = $1`,
+			imp.ID, imp.ProcessedRows, imp.CreatedRows, imp.FailedRows)
+		if err != nil {
+			return mapError(err)
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+
+		values := make([]string, 0, len(errs))
+		args := []interface{}{imp.ID}
+		for _, e := range errs {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($1, $%d, $%d, $%d)", n+1, n+2, n+3))
+			args = append(args, e.Row, pq.Array(e.Record), e.Error)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO import_errors (import_id, row_number, record, error)
+			VALUES `+strings.Join(values, ", ")+`
+			ON CONFLICT (import_id, row_number) DO UPDATE SET record = EXCLUDED.record, error = EXCLUDED.error`,
+			args...)
+		return mapError(err)
+	})
+}
+
+func (r *ImportRepository) FinishImport(ctx context.Context, id uuid.UUID, status, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE imports SET status = $2, error = NULLIF($3, ''), finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1`,
+		id, status, errMsg)
+	return mapError(err)
+}
+
+func (r *ImportRepository) ImportErrors(ctx context.Context, id uuid.UUID) ([]models.ImportError, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT row_number, record, error FROM import_errors
+		WHERE import_id = $1
+		ORDER BY row_number`, id)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	errs := []models.ImportError{}
+	for rows.Next() {
+		var e models.ImportError
+		if err := rows.Scan(&e.Row, pq.Array(&e.Record), &e.Error); err != nil {
+			return nil, err
+		}
+		errs = append(errs, e)
+	}
+	return errs, rows.Err()
+}
+
+func scanImport(row scanner) (*models.Import, error) {
+	var imp models.Import
+	var mapping []byte
+	var errMsg sql.NullString
+	var finishedAt sql.NullTime
+	err := row.Scan(&imp.ID, &imp.Status, &imp.Format, &mapping, &imp.Delimiter, pq.Array(&imp.Columns), &imp.TotalRows, &imp.ProcessedRows,
+		&imp.CreatedRows, &imp.FailedRows, &imp.Attempts, &errMsg, &imp.CreatedBy, &imp.CreatedAt, &imp.UpdatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	imp.Error = errMsg.String
+	if finishedAt.Valid {
+		imp.FinishedAt = &finishedAt.Time
+	}
+	if err := json.Unmarshal(mapping, &imp.Mapping); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
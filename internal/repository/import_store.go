@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+)
+
+// ImportStore - хранилище фоновых импортов. Реализации: ImportRepository (Postgres) и MemoryImportRepository.
+type ImportStore interface {
+	CreateImport(ctx context.Context, imp models.Import) error
+	GetImport(ctx context.Context, id uuid.UUID) (*models.Import, error)
+	// ClaimImport берет в работу самый старый ожидающий импорт или выполняющийся импорт,
+	// который не сохранял прогресс дольше lease (обработчик упал или сервер перезапустили),
+	// и увеличивает Attempts. Если брать нечего, возвращает ErrNotFound.
+	ClaimImport(ctx context.Context, lease time.Duration) (*models.Import, error)
+	// SaveProgress сохраняет счетчики и ошибочные строки и продлевает аренду импорта.
+	// Повторно сохраненная ошибка той же строки заменяет прежнюю.
+	SaveProgress(ctx context.Context, imp *models.Import, errs []models.ImportError) error
+	FinishImport(ctx context.Context, id uuid.UUID, status, errMsg string) error
+	ImportErrors(ctx context.Context, id uuid.UUID) ([]models.ImportError, error)
+}
+
+var (
+	_ ImportStore = (*ImportRepository)(nil)
+	_ ImportStore = (*MemoryImportRepository)(nil)
+)
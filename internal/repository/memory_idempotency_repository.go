@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"effective-mobile-task/internal/models"
+)
+
+// MemoryIdempotencyRepository хранит ключи идемпотентности в памяти процесса.
+type MemoryIdempotencyRepository struct {
+	*memoryIdempotencyKeys
+	// undo - журнал транзакции MemoryUnitOfWork, вне транзакции nil
+	undo *memoryUndo
+}
+
+type memoryIdempotencyKeys struct {
+	mu   sync.Mutex
+	keys map[[2]string]*memoryIdempotencyKey
+}
+
+type memoryIdempotencyKey struct {
+	fingerprint string
+	response    *models.IdempotentResponse
+	lockedAt    time.Time
+	expiresAt   time.Time
+}
+
+func NewMemoryIdempotencyRepository() *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{memoryIdempotencyKeys: &memoryIdempotencyKeys{keys: make(map[[2]string]*memoryIdempotencyKey)}}
+}
+
+func (r *MemoryIdempotencyRepository) withUndo(undo *memoryUndo) *MemoryIdempotencyRepository {
+	return &MemoryIdempotencyRepository{memoryIdempotencyKeys: r.memoryIdempotencyKeys, undo: undo}
+}
+
+// remember записывает в журнал транзакции возврат ключа id к текущему состоянию. Вызывается под r.mu до изменения.
+func (r *MemoryIdempotencyRepository) remember(id [2]string) {
+	var prev *memoryIdempotencyKey
+	if k, ok := r.keys[id]; ok {
+		// SaveResponse меняет ключ на месте, поэтому запоминается копия
+		copied := *k
+		prev = &copied
+	}
+	data := r.memoryIdempotencyKeys
+	r.undo.add(func() {
+		data.mu.Lock()
+		defer data.mu.Unlock()
+		if prev != nil {
+			data.keys[id] = prev
+		} else {
+			delete(data.keys, id)
+		}
+	})
+}
+
+func (r *MemoryIdempotencyRepository) AcquireKey(ctx context.Context, scope, key, fingerprint string, ttl, lockTimeout time.Duration) (*models.IdempotentResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	k, ok := r.keys[[2]string{scope, key}]
+	switch {
+	case !ok, now.After(k.expiresAt),
+		k.response == nil && k.fingerprint == fingerprint && now.Sub(k.lockedAt) > lockTimeout:
+		r.remember([2]string{scope, key})
+		r.keys[[2]string{scope, key}] = &memoryIdempotencyKey{fingerprint: fingerprint, lockedAt: now, expiresAt: now.Add(ttl)}
+		return nil, nil
+	case k.fingerprint != fingerprint:
+		return nil, ErrKeyMismatch
+	case k.response == nil:
+		return nil, ErrKeyInProgress
+	}
+	resp := *k.response
+	resp.Header = maps.Clone(resp.Header)
+	resp.Body = slices.Clone(resp.Body)
+	return &resp, nil
+}
+
+func (r *MemoryIdempotencyRepository) SaveResponse(ctx context.Context, scope, key string, resp models.IdempotentResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.keys[[2]string{scope, key}]; ok {
+		resp.Header = maps.Clone(resp.Header)
+		resp.Body = slices.Clone(resp.Body)
+		r.remember([2]string{scope, key})
+		k.response = &resp
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) ReleaseKey(ctx context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.keys[[2]string{scope, key}]; ok && k.response == nil {
+		r.remember([2]string{scope, key})
+		delete(r.keys, [2]string{scope, key})
+	}
+	return nil
+}
+
+func (r *MemoryIdempotencyRepository) PurgeExpiredKeys(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var n int64
+	for id, k := range r.keys {
+		if now.After(k.expiresAt) {
+			r.remember(id)
+			delete(r.keys, id)
+			n++
+		}
+	}
+	return n, nil
+}
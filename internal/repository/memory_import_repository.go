@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+)
+
+// MemoryImportRepository хранит импорты в памяти процесса; после перезапуска они теряются.
+type MemoryImportRepository struct {
+	*memoryImports
+	// undo - журнал транзакции MemoryUnitOfWork, вне транзакции nil
+	undo *memoryUndo
+}
+
+type memoryImports struct {
+	mu        sync.Mutex
+	imports   map[uuid.UUID]models.Import
+	heartbeat map[uuid.UUID]time.Time
+	errors    map[uuid.UUID]map[int]models.ImportError
+}
+
+func NewMemoryImportRepository() *MemoryImportRepository {
+	return &MemoryImportRepository{memoryImports: &memoryImports{
+		imports:   make(map[uuid.UUID]models.Import),
+		heartbeat: make(map[uuid.UUID]time.Time),
+		errors:    make(map[uuid.UUID]map[int]models.ImportError),
+	}}
+}
+
+func (r *MemoryImportRepository) withUndo(undo *memoryUndo) *MemoryImportRepository {
+	return &MemoryImportRepository{memoryImports: r.memoryImports, undo: undo}
+}
+
+// remember записывает в журнал транзакции возврат импорта id к текущему состоянию. Вызывается под r.mu до изменения.
+func (r *MemoryImportRepository) remember(id uuid.UUID) {
+	imp, existed := r.imports[id]
+	heartbeat, beating := r.heartbeat[id]
+	errs := maps.Clone(r.errors[id])
+	data := r.memoryImports
+	r.undo.add(func() {
+		data.mu.Lock()
+		defer data.mu.Unlock()
+		if existed {
+			data.imports[id] = imp
+		} else {
+			delete(data.imports, id)
+		}
+		if beating {
+			data.heartbeat[id] = heartbeat
+		} else {
+			delete(data.heartbeat, id)
+		}
+		if errs != nil {
+			data.errors[id] = errs
+		} else {
+			delete(data.errors, id)
+		}
+	})
+}
+
+func (r *MemoryImportRepository) CreateImport(ctx context.Context, imp models.Import) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.imports[imp.ID]; exists {
+		return fmt.Errorf("%w: import %s already exists", ErrConflict, imp.ID)
+	}
+	now := time.Now()
+	imp.CreatedAt, imp.UpdatedAt = now, now
+	r.remember(imp.ID)
+	r.imports[imp.ID] = cloneImport(imp)
+	return nil
+}
+
+func (r *MemoryImportRepository) GetImport(ctx context.Context, id uuid.UUID) (*models.Import, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imp, ok := r.imports[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	imp = cloneImport(imp)
+	imp.Data = nil
+	return &imp, nil
+}
+
+func (r *MemoryImportRepository) ClaimImport(ctx context.Context, lease time.Duration) (*models.Import, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var claimed *models.Import
+	for _, imp := range r.imports {
+		stale := imp.Status == models.ImportRunning && now.Sub(r.heartbeat[imp.ID]) > lease
+		if imp.Status != models.ImportPending && !stale {
+			continue
+		}
+		if claimed == nil || imp.CreatedAt.Before(claimed.CreatedAt) {
+			claimed = &imp
+		}
+	}
+	if claimed == nil {
+		return nil, ErrNotFound
+	}
+
+	claimed.Status = models.ImportRunning
+	claimed.Attempts++
+	claimed.UpdatedAt = now
+	r.remember(claimed.ID)
+	r.imports[claimed.ID] = *claimed
+	r.heartbeat[claimed.ID] = now
+
+	imp := cloneImport(*claimed)
+	return &imp, nil
+}
+
+func (r *MemoryImportRepository) SaveProgress(ctx context.Context, imp *models.Import, errs []models.ImportError) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.imports[imp.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	r.remember(imp.ID)
+	now := time.Now()
+	stored.ProcessedRows, stored.CreatedRows, stored.FailedRows = imp.ProcessedRows, imp.CreatedRows, imp.FailedRows
+	stored.UpdatedAt = now
+	r.imports[imp.ID] = stored
+	r.heartbeat[imp.ID] = now
+
+	if r.errors[imp.ID] == nil {
+		r.errors[imp.ID] = make(map[int]models.ImportError)
+	}
+	for _, e := range errs {
+		e.Record = slices.Clone(e.Record)
+		r.errors[imp.ID][e.Row] = e
+	}
+	return nil
+}
+
+func (r *MemoryImportRepository) FinishImport(ctx context.Context, id uuid.UUID, status, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imp, ok := r.imports[id]
+	if !ok {
+		return ErrNotFound
+	}
+	r.remember(id)
+	now := time.Now()
+	imp.Status, imp.Error = status, errMsg
+	imp.FinishedAt, imp.UpdatedAt = &now, now
+	r.imports[id] = imp
+	return nil
+}
+
+func (r *MemoryImportRepository) ImportErrors(ctx context.Context, id uuid.UUID) ([]models.ImportError, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.imports[id]; !ok {
+		return nil, ErrNotFound
+	}
+	errs := []models.ImportError{}
+	for _, row := range slices.Sorted(maps.Keys(r.errors[id])) {
+		e := r.errors[id][row]
+		e.Record = slices.Clone(e.Record)
+		errs = append(errs, e)
+	}
+	return errs, nil
+}
+
+func cloneImport(imp models.Import) models.Import {
+	imp.Mapping = maps.Clone(imp.Mapping)
+	imp.Columns = slices.Clone(imp.Columns)
+	imp.Data = bytes.Clone(imp.Data)
+	if imp.FinishedAt != nil {
+		finishedAt := *imp.FinishedAt
+		imp.FinishedAt = &finishedAt
+	}
+	return imp
+}
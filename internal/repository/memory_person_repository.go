@@ -0,0 +1,762 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/search"
+	"github.com/google/uuid"
+)
+
+// MemoryPersonRepository хранит людей в памяти процесса с той же семантикой фильтров, что и PersonRepository.
+// Копии, полученные через withUndo, работают с теми же данными и записывают отмену изменений в журнал транзакции.
+type MemoryPersonRepository struct {
+	*memoryPersons
+	// undo - журнал транзакции MemoryUnitOfWork, вне транзакции nil
+	undo *memoryUndo
+}
+
+type memoryPersons struct {
+	mu      sync.RWMutex
+	persons map[uuid.UUID]models.Person
+	history []models.PersonHistoryEntry
+	// historySeq - последний выданный id записи истории; после отката id не переиспользуются, как у bigserial
+	historySeq int64
+	// mergedInto - поглощенный при слиянии id -> id выжившего
+	mergedInto map[uuid.UUID]uuid.UUID
+	// uniqueNames повторяет необязательный уникальный индекс по name_key
+	uniqueNames bool
+}
+
+// NewMemoryPersonRepository создает хранилище; uniqueNames запрещает двух неудаленных людей
+// с одинаковым NameKey, как миграция optional/000001_unique_person_names в Postgres.
+func NewMemoryPersonRepository(uniqueNames bool) *MemoryPersonRepository {
+	return &MemoryPersonRepository{memoryPersons: &memoryPersons{
+		persons:     make(map[uuid.UUID]models.Person),
+		mergedInto:  make(map[uuid.UUID]uuid.UUID),
+		uniqueNames: uniqueNames,
+	}}
+}
+
+func (r *MemoryPersonRepository) withUndo(undo *memoryUndo) *MemoryPersonRepository {
+	return &MemoryPersonRepository{memoryPersons: r.memoryPersons, undo: undo}
+}
+
+// remember записывает в журнал транзакции возврат записи id к текущему состоянию. Вызывается под r.mu до изменения.
+func (r *MemoryPersonRepository) remember(id uuid.UUID) {
+	prev, existed := r.persons[id]
+	data := r.memoryPersons
+	r.undo.add(func() {
+		data.mu.Lock()
+		defer data.mu.Unlock()
+		if existed {
+			data.persons[id] = prev
+		} else {
+			delete(data.persons, id)
+		}
+	})
+}
+
+// rememberMerged - то же, что remember, для связи в mergedInto.
+func (r *MemoryPersonRepository) rememberMerged(id uuid.UUID) {
+	prev, existed := r.mergedInto[id]
+	data := r.memoryPersons
+	r.undo.add(func() {
+		data.mu.Lock()
+		defer data.mu.Unlock()
+		if existed {
+			data.mergedInto[id] = prev
+		} else {
+			delete(data.mergedInto, id)
+		}
+	})
+}
+
+func (r *MemoryPersonRepository) Create(ctx context.Context, p models.Person) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.persons[p.ID]; exists {
+		return fmt.Errorf("%w: person %s already exists", ErrConflict, p.ID)
+	}
+	if r.uniqueNames && r.nameTaken(p) {
+		return errNameTaken
+	}
+
+	now := time.Now()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	r.remember(p.ID)
+	r.persons[p.ID] = clonePerson(p)
+	r.recordHistory(ctx, p.ID, models.HistoryCreate, nil, &p)
+	return nil
+}
+
+func (r *MemoryPersonRepository) CreateMany(ctx context.Context, people []models.Person) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[uuid.UUID]bool, len(people))
+	keys := make(map[string]bool, len(people))
+	for _, p := range people {
+		if _, exists := r.persons[p.ID]; exists || seen[p.ID] {
+			return fmt.Errorf("%w: person %s already exists", ErrConflict, p.ID)
+		}
+		key := NameKey(p.Name, p.Surname, p.Patronymic)
+		if r.uniqueNames && (keys[key] || r.nameTaken(p)) {
+			return errNameTaken
+		}
+		seen[p.ID], keys[key] = true, true
+	}
+
+	now := time.Now()
+	for _, p := range people {
+		p.CreatedAt = now
+		p.UpdatedAt = now
+		r.remember(p.ID)
+		r.persons[p.ID] = clonePerson(p)
+		r.recordHistory(ctx, p.ID, models.HistoryCreate, nil, &p)
+	}
+	return nil
+}
+
+func (r *MemoryPersonRepository) Update(ctx context.Context, id uuid.UUID, version int, update models.UpdatePersonRequest) (*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.persons[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	if version > 0 && p.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	if update.Name != nil || update.Surname != nil || update.Patronymic != nil {
+		before := p
+		if update.Name != nil {
+			p.Name = *update.Name
+		}
+		if update.Surname != nil {
+			p.Surname = *update.Surname
+		}
+		if update.Patronymic != nil {
+			p.Patronymic = *update.Patronymic
+		}
+		if update.NameOriginal != nil {
+			p.NameOriginal = *update.NameOriginal
+		}
+		if update.SurnameOriginal != nil {
+			p.SurnameOriginal = *update.SurnameOriginal
+		}
+		if update.PatronymicOriginal != nil {
+			p.PatronymicOriginal = *update.PatronymicOriginal
+		}
+		if r.uniqueNames && r.nameTaken(p) {
+			return nil, errNameTaken
+		}
+		p.UpdatedAt = time.Now()
+		p.Version++
+		r.remember(id)
+		r.persons[id] = p
+		r.recordHistory(ctx, id, models.HistoryUpdate, &before, &p)
+	}
+
+	p = clonePerson(p)
+	return &p, nil
+}
+
+func (r *MemoryPersonRepository) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.persons[id]
+	if !ok || p.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if version > 0 && p.Version != version {
+		return ErrVersionMismatch
+	}
+
+	before := p
+	now := time.Now()
+	p.DeletedAt = &now
+	p.Version++
+	r.remember(id)
+	r.persons[id] = p
+	r.recordHistory(ctx, id, models.HistoryDelete, &before, &p)
+	return nil
+}
+
+func (r *MemoryPersonRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.persons[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if p.DeletedAt == nil {
+		return nil, ErrNotDeleted
+	}
+	if r.uniqueNames && r.nameTaken(p) {
+		return nil, errNameTaken
+	}
+
+	before := p
+	p.DeletedAt = nil
+	p.UpdatedAt = time.Now()
+	p.Version++
+	r.remember(id)
+	r.persons[id] = p
+	r.recordHistory(ctx, id, models.HistoryRestore, &before, &p)
+
+	p = clonePerson(p)
+	return &p, nil
+}
+
+func (r *MemoryPersonRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, p := range r.persons {
+		if p.DeletedAt != nil && p.DeletedAt.Before(deletedBefore) {
+			r.remember(id)
+			delete(r.persons, id)
+			r.recordHistory(ctx, id, models.HistoryPurge, &p, nil)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *MemoryPersonRepository) FindDuplicate(ctx context.Context, req models.CreatePersonRequest, threshold float64) (*models.Person, error) {
+	key := NameKey(req.Name, req.Surname, req.Patronymic)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *models.Person
+	bestScore := 0.0
+	for _, p := range r.persons {
+		if p.DeletedAt != nil {
+			continue
+		}
+		other := NameKey(p.Name, p.Surname, p.Patronymic)
+		score := 0.0
+		switch {
+		case other == key:
+			score = 1
+		case threshold > 0:
+			score = search.Similarity(other, key)
+		}
+		if score == 0 || score < threshold {
+			continue
+		}
+		if best == nil || score > bestScore || score == bestScore && p.CreatedAt.Before(best.CreatedAt) {
+			found := clonePerson(p)
+			best, bestScore = &found, score
+		}
+	}
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	return best, nil
+}
+
+func (r *MemoryPersonRepository) SimilarPairs(ctx context.Context, threshold float64, limit int) ([]SimilarPair, error) {
+	r.mu.RLock()
+	var people []models.Person
+	for _, p := range r.persons {
+		if p.DeletedAt == nil {
+			people = append(people, p)
+		}
+	}
+	r.mu.RUnlock()
+
+	var pairs []SimilarPair
+	for i, a := range people {
+		for _, b := range people[i+1:] {
+			score := search.Similarity(NameKey(a.Name, a.Surname, a.Patronymic), NameKey(b.Name, b.Surname, b.Patronymic))
+			if score < threshold {
+				continue
+			}
+			if a.ID.String() > b.ID.String() {
+				a, b = b, a
+			}
+			pairs = append(pairs, SimilarPair{A: a.ID, B: b.ID, Similarity: score})
+		}
+	}
+	slices.SortFunc(pairs, func(x, y SimilarPair) int {
+		return cmp.Or(cmp.Compare(y.Similarity, x.Similarity), strings.Compare(x.A.String(), y.A.String()), strings.Compare(x.B.String(), y.B.String()))
+	})
+	if limit < len(pairs) {
+		pairs = pairs[:limit]
+	}
+	return pairs, nil
+}
+
+func (r *MemoryPersonRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Person, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var people []models.Person
+	for _, id := range ids {
+		if p, ok := r.persons[id]; ok && p.DeletedAt == nil {
+			people = append(people, clonePerson(p))
+		}
+	}
+	return people, nil
+}
+
+func (r *MemoryPersonRepository) Merge(ctx context.Context, survivorID uuid.UUID, mergedIDs []uuid.UUID, resolve MergeFunc) (*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	locked := map[uuid.UUID]*models.Person{}
+	for _, id := range append([]uuid.UUID{survivorID}, mergedIDs...) {
+		if p, ok := r.persons[id]; ok && p.DeletedAt == nil {
+			p = clonePerson(p)
+			locked[id] = &p
+		}
+	}
+	before, merged, err := mergeParticipants(locked, survivorID, mergedIDs)
+	if err != nil {
+		return nil, err
+	}
+	result, err := resolve(*before, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	survivor := *before
+	survivor.Name, survivor.Surname, survivor.Patronymic = result.Name, result.Surname, result.Patronymic
+	survivor.NameOriginal, survivor.SurnameOriginal, survivor.PatronymicOriginal = result.NameOriginal, result.SurnameOriginal, result.PatronymicOriginal
+	survivor.Age, survivor.BirthYear = result.Age, result.BirthYear
+	survivor.Gender, survivor.Nationality = result.Gender, result.Nationality
+	// проверка ФИО без учета поглощаемых: в Postgres они к этому моменту уже удалены
+	key := NameKey(survivor.Name, survivor.Surname, survivor.Patronymic)
+	for id, p := range r.persons {
+		if r.uniqueNames && id != survivorID && p.DeletedAt == nil && !slices.Contains(mergedIDs, id) && NameKey(p.Name, p.Surname, p.Patronymic) == key {
+			return nil, errNameTaken
+		}
+	}
+
+	now := time.Now()
+	for _, p := range merged {
+		deleted := p
+		deleted.DeletedAt = &now
+		deleted.UpdatedAt = now
+		deleted.Version++
+		r.remember(p.ID)
+		r.persons[p.ID] = deleted
+		r.recordHistory(ctx, p.ID, models.HistoryMerge, &p, &deleted)
+	}
+	for from, to := range r.mergedInto {
+		if slices.Contains(mergedIDs, to) {
+			r.rememberMerged(from)
+			r.mergedInto[from] = survivorID
+		}
+	}
+	for _, id := range mergedIDs {
+		r.rememberMerged(id)
+		r.mergedInto[id] = survivorID
+	}
+
+	survivor.UpdatedAt = now
+	survivor.Version++
+	r.remember(survivorID)
+	r.persons[survivorID] = survivor
+	r.recordHistory(ctx, survivorID, models.HistoryMerge, before, &survivor)
+
+	survivor = clonePerson(survivor)
+	return &survivor, nil
+}
+
+func (r *MemoryPersonRepository) MergedInto(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	survivorID, ok := r.mergedInto[id]
+	if !ok {
+		return uuid.Nil, ErrNotFound
+	}
+	return survivorID, nil
+}
+
+func (r *MemoryPersonRepository) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*models.Person, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.persons[id]
+	if !ok || (p.DeletedAt != nil && !includeDeleted) {
+		return nil, ErrNotFound
+	}
+	p = clonePerson(p)
+	return &p, nil
+}
+
+func (r *MemoryPersonRepository) GetAll(ctx context.Context, limit, offset int, f filter.Filter, sort Sort) ([]models.Person, error) {
+	match := memoryFilter(f, time.Now().Year())
+
+	r.mu.RLock()
+	var people []models.Person
+	for _, p := range r.persons {
+		if match(p) {
+			people = append(people, clonePerson(p))
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(people, func(a, b models.Person) int {
+		return sort.compare(sort.values(a), sort.values(b))
+	})
+
+	if offset >= len(people) {
+		return nil, nil
+	}
+	people = people[offset:]
+	if limit < len(people) {
+		people = people[:limit]
+	}
+	return people, nil
+}
+
+func (r *MemoryPersonRepository) GetPage(ctx context.Context, limit int, cursor *Cursor, f filter.Filter, sort Sort) (*Page, error) {
+	match := memoryFilter(f, time.Now().Year())
+	var at []interface{}
+	backward := false
+	if cursor != nil {
+		var err error
+		if at, err = sort.cursorValues(cursor); err != nil {
+			return nil, err
+		}
+		backward = cursor.Backward
+	}
+
+	r.mu.RLock()
+	var people []models.Person
+	for _, p := range r.persons {
+		if !match(p) {
+			continue
+		}
+		// запись должна лежать строго за курсором в направлении выборки
+		if at != nil {
+			if c := sort.compare(sort.values(p), at); c == 0 || (c > 0) == backward {
+				continue
+			}
+		}
+		people = append(people, clonePerson(p))
+	}
+	r.mu.RUnlock()
+
+	// для страницы назад порядок обратный, как в PersonRepository.GetPage
+	slices.SortFunc(people, func(a, b models.Person) int {
+		c := sort.compare(sort.values(a), sort.values(b))
+		if backward {
+			return -c
+		}
+		return c
+	})
+	if limit+1 < len(people) {
+		people = people[:limit+1]
+	}
+	return newPage(people, limit, sort, cursor), nil
+}
+
+// Export сортирует копию подходящих записей и передает их fn уже без блокировки.
+func (r *MemoryPersonRepository) Export(ctx context.Context, f filter.Filter, sort Sort, fn func(models.Person) error) error {
+	people, err := r.GetAll(ctx, math.MaxInt, 0, f, sort)
+	if err != nil {
+		return err
+	}
+	for _, p := range people {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPersonRepository) Count(ctx context.Context, f filter.Filter, estimate bool) (int64, bool, error) {
+	match := memoryFilter(f, time.Now().Year())
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var total int64
+	for _, p := range r.persons {
+		if match(p) {
+			total++
+		}
+	}
+	return total, false, nil
+}
+
+// Search повторяет отбор и ранжирование PersonRepository.Search: все слова запроса есть в ФИО
+// или хотя бы одно похоже на имя, фамилию или отчество не меньше search.Threshold;
+// оценка - среднее лучшее сходство плюс ts_rank. Составные слова через дефис tsvector
+// разбирает иначе, поэтому у таких ФИО ранг может немного отличаться.
+func (r *MemoryPersonRepository) Search(ctx context.Context, query string, limit int) ([]models.PersonSearchResult, error) {
+	tokens := search.Tokens(query)
+	results := []models.PersonSearchResult{}
+	if len(tokens) == 0 {
+		return results, nil
+	}
+
+	r.mu.RLock()
+	for _, p := range r.persons {
+		if p.DeletedAt != nil {
+			continue
+		}
+		doc := p.Name + " " + p.Surname + " " + p.Patronymic
+		matched := search.ContainsAll(doc, tokens)
+		var similarity float64
+		for _, t := range tokens {
+			best := 0.0
+			for _, field := range []string{p.Name, p.Surname, p.Patronymic} {
+				sim := search.Similarity(field, t)
+				best = max(best, sim)
+				if sim >= search.Threshold {
+					matched = true
+				}
+			}
+			similarity += best
+		}
+		if matched {
+			score := similarity/float64(len(tokens)) + search.Rank(doc, tokens)
+			results = append(results, models.PersonSearchResult{Person: clonePerson(p), Score: score})
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(results, func(a, b models.PersonSearchResult) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID.String(), b.ID.String())
+	})
+	if limit < len(results) {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (r *MemoryPersonRepository) History(ctx context.Context, personID uuid.UUID, limit, offset int) ([]models.PersonHistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []models.PersonHistoryEntry{}
+	// история хранится в порядке записи, а отдается от новых к старым
+	for i := len(r.history) - 1; i >= 0; i-- {
+		if r.history[i].PersonID != personID {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(entries) == limit {
+			break
+		}
+		entries = append(entries, cloneHistoryEntry(r.history[i]))
+	}
+	return entries, nil
+}
+
+func (r *MemoryPersonRepository) GetAsOf(ctx context.Context, personID uuid.UUID, at time.Time) (*models.Person, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.history) - 1; i >= 0; i-- {
+		e := r.history[i]
+		if e.PersonID != personID || e.ChangedAt.After(at) {
+			continue
+		}
+		if e.After == nil {
+			return nil, ErrNotFound
+		}
+		p := clonePerson(*e.After)
+		return &p, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPersonRepository) recordHistory(ctx context.Context, personID uuid.UUID, action string, before, after *models.Person) {
+	r.historySeq++
+	e := models.PersonHistoryEntry{
+		ID:        r.historySeq,
+		PersonID:  personID,
+		Action:    action,
+		ChangedBy: actor.FromContext(ctx),
+		ChangedAt: time.Now(),
+	}
+	if before != nil {
+		b := clonePerson(*before)
+		e.Before = &b
+	}
+	if after != nil {
+		a := clonePerson(*after)
+		e.After = &a
+	}
+	r.history = append(r.history, e)
+
+	data := r.memoryPersons
+	r.undo.add(func() {
+		data.mu.Lock()
+		defer data.mu.Unlock()
+		data.history = slices.DeleteFunc(data.history, func(h models.PersonHistoryEntry) bool { return h.ID == e.ID })
+	})
+}
+
+func cloneHistoryEntry(e models.PersonHistoryEntry) models.PersonHistoryEntry {
+	if e.Before != nil {
+		b := clonePerson(*e.Before)
+		e.Before = &b
+	}
+	if e.After != nil {
+		a := clonePerson(*e.After)
+		e.After = &a
+	}
+	return e
+}
+
+// memoryFilter повторяет условия WHERE из filterConditions.
+func memoryFilter(f filter.Filter, currentYear int) func(models.Person) bool {
+	conds := make([]func(models.Person) bool, 0, len(f.Conditions)+1)
+	if !f.IncludeDeleted {
+		conds = append(conds, func(p models.Person) bool { return p.DeletedAt == nil })
+	}
+	for _, c := range f.Conditions {
+		match := memoryCondition(c, currentYear)
+		if c.Not {
+			// NOT от NULL в Postgres тоже не проходит: запись без года рождения не подходит ни под одно условие по возрасту
+			conds = append(conds, func(p models.Person) bool { return (c.Field != "age" || p.BirthYear != 0) && !match(p) })
+		} else {
+			conds = append(conds, match)
+		}
+	}
+
+	return func(p models.Person) bool {
+		for _, cond := range conds {
+			if !cond(p) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func memoryCondition(c filter.Condition, currentYear int) func(models.Person) bool {
+	switch c.Field {
+	case "age":
+		return func(p models.Person) bool {
+			return p.BirthYear != 0 && matchOrdered(c, currentYear-p.BirthYear, func(v interface{}) int { return v.(int) })
+		}
+	case "created_at", "updated_at":
+		return func(p models.Person) bool {
+			t := p.CreatedAt
+			if c.Field == "updated_at" {
+				t = p.UpdatedAt
+			}
+			return matchTime(c, t)
+		}
+	}
+
+	get := map[string]func(models.Person) string{
+		"name":        func(p models.Person) string { return p.Name },
+		"surname":     func(p models.Person) string { return p.Surname },
+		"patronymic":  func(p models.Person) string { return p.Patronymic },
+		"gender":      func(p models.Person) string { return p.Gender },
+		"nationality": func(p models.Person) string { return p.Nationality },
+	}[c.Field]
+	text := filter.KindOf(c.Field) == filter.KindText
+
+	return func(p models.Person) bool {
+		s := get(p)
+		if c.Op == filter.OpNull {
+			return (s == "") == c.Values[0].(bool)
+		}
+		for _, v := range c.Values {
+			want := v.(string)
+			switch {
+			case !text && s == want,
+				text && c.Op == filter.OpPrefix && strings.HasPrefix(strings.ToLower(s), strings.ToLower(want)),
+				text && c.Op == filter.OpContains && containsFold(s, want),
+				text && (c.Op == filter.OpEq || c.Op == filter.OpIn) && strings.EqualFold(s, want):
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matchOrdered проверяет условие для упорядоченного значения.
+func matchOrdered[T cmp.Ordered](c filter.Condition, x T, value func(interface{}) T) bool {
+	switch c.Op {
+	case filter.OpGt:
+		return x > value(c.Values[0])
+	case filter.OpGte:
+		return x >= value(c.Values[0])
+	case filter.OpLt:
+		return x < value(c.Values[0])
+	case filter.OpLte:
+		return x <= value(c.Values[0])
+	case filter.OpBetween:
+		return x >= value(c.Values[0]) && x <= value(c.Values[1])
+	case filter.OpRange:
+		return x >= value(c.Values[0]) && x < value(c.Values[1])
+	}
+	for _, v := range c.Values {
+		if x == value(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTime(c filter.Condition, t time.Time) bool {
+	return matchOrdered(c, t.UnixNano(), func(v interface{}) int64 { return v.(time.Time).UnixNano() })
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// errNameTaken повторяет нарушение необязательного уникального индекса по name_key в Postgres.
+var errNameTaken = fmt.Errorf("%w: person with the same name already exists", ErrConflict)
+
+// nameTaken сообщает, есть ли другой неудаленный человек с тем же NameKey, что у p.
+func (r *MemoryPersonRepository) nameTaken(p models.Person) bool {
+	key := NameKey(p.Name, p.Surname, p.Patronymic)
+	for id, other := range r.persons {
+		if id != p.ID && other.DeletedAt == nil && NameKey(other.Name, other.Surname, other.Patronymic) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func clonePerson(p models.Person) models.Person {
+	if p.DeletedAt != nil {
+		deletedAt := *p.DeletedAt
+		p.DeletedAt = &deletedAt
+	}
+	if p.EnrichmentSources != nil {
+		p.EnrichmentSources = append([]models.EnrichmentSource(nil), p.EnrichmentSources...)
+	}
+	return p
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MemoryUnitOfWork выполняет транзакции над хранилищами в памяти по одной за раз.
+// Хранилища транзакции меняют общие данные сразу и записывают обратные операции в журнал:
+// если fn вернула ошибку, изменения откатываются. Изоляции нет - незафиксированные
+// изменения видны вызовам хранилищ вне транзакции.
+type MemoryUnitOfWork struct {
+	mu          sync.Mutex
+	persons     *MemoryPersonRepository
+	imports     *MemoryImportRepository
+	idempotency *MemoryIdempotencyRepository
+}
+
+func NewMemoryUnitOfWork(persons *MemoryPersonRepository, imports *MemoryImportRepository, idempotency *MemoryIdempotencyRepository) *MemoryUnitOfWork {
+	return &MemoryUnitOfWork{persons: persons, imports: imports, idempotency: idempotency}
+}
+
+func (u *MemoryUnitOfWork) WithTx(ctx context.Context, fn func(tx Stores) error) (err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	undo := &memoryUndo{}
+	defer func() {
+		if p := recover(); p != nil {
+			undo.rollback()
+			panic(p)
+		}
+		if err != nil {
+			undo.rollback()
+		}
+	}()
+	return fn(Stores{
+		Persons:     u.persons.withUndo(undo),
+		Imports:     u.imports.withUndo(undo),
+		Idempotency: u.idempotency.withUndo(undo),
+	})
+}
+
+// memoryUndo - журнал обратных операций транзакции MemoryUnitOfWork.
+// Каждая операция сама берет блокировку своего хранилища.
+type memoryUndo struct {
+	mu  sync.Mutex
+	ops []func()
+}
+
+// add добавляет обратную операцию; вне транзакции (undo == nil) ничего не делает.
+func (u *memoryUndo) add(op func()) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ops = append(u.ops, op)
+}
+
+// rollback выполняет обратные операции от последней к первой.
+func (u *memoryUndo) rollback() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, op := range slices.Backward(u.ops) {
+		op()
+	}
+	u.ops = nil
+}
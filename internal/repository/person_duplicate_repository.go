@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"effective-mobile-task/internal/models"
+)
+
+// NameKey нормализует ФИО для поиска дубликатов: нижний регистр, пробелы схлопнуты,
+// части разделены "|". Совпадает со столбцом persons.name_key.
+func NameKey(name, surname, patronymic string) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.Join(strings.Fields(s), " "))
+	}
+	return normalize(name) + "|" + normalize(surname) + "|" + normalize(patronymic)
+}
+
+// FindDuplicate при threshold = 0 ищет по индексу name_key самую раннюю запись с тем же ФИО, иначе отбирает
+// кандидатов по триграммному индексу и берет самого похожего. Отбор по индексу использует
+// pg_trgm.similarity_threshold, поэтому threshold ниже него работает как этот порог.
+func (r *PersonRepository) FindDuplicate(ctx context.Context, req models.CreatePersonRequest, threshold float64) (*models.Person, error) {
+	key := NameKey(req.Name, req.Surname, req.Patronymic)
+	var row *sql.Row
+	if threshold <= 0 {
+		row = r.db.QueryRowContext(ctx, `
+			SELECT `+personColumns+` FROM persons
+			WHERE name_key = $1 AND deleted_at IS NULL
+			ORDER BY created_at, id
+			LIMIT 1`, key)
+	} else {
+		row = r.db.QueryRowContext(ctx, `
+			SELECT `+personColumns+` FROM persons
+			WHERE name_key % $1 AND similarity(name_key, $1) >= $2 AND deleted_at IS NULL
+			ORDER BY similarity(name_key, $1) DESC, created_at, id
+			LIMIT 1`, key, threshold)
+	}
+	p, err := scanPerson(row)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return p, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+)
+
+// exportFetchSize - сколько строк выгрузки читается из курсора за один FETCH.
+const exportFetchSize = 1000
+
+// exportIdleTimeout - сколько транзакция выгрузки ждет между FETCH, пока строки уходят клиенту.
+// Если клиент читает медленнее, Postgres завершает сессию, и соединение не держит снимок бесконечно.
+const exportIdleTimeout = 5 * time.Minute
+
+// Export читает людей через серверный курсор порциями по exportFetchSize,
+// так что в памяти одновременно находится не больше одной порции.
+func (r *PersonRepository) Export(ctx context.Context, f filter.Filter, sort Sort, fn func(models.Person) error) error {
+	where, args := filterConditions(f)
+	query := `SELECT ` + personColumns + ` FROM persons WHERE 1=1` + where + sort.orderBy(false)
+
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		setTimeout := fmt.Sprintf("SET LOCAL idle_in_transaction_session_timeout = %d", exportIdleTimeout.Milliseconds())
+		if _, err := tx.ExecContext(ctx, setTimeout); err != nil {
+			return mapError(err)
+		}
+		if _, err := tx.ExecContext(ctx, `DECLARE persons_export NO SCROLL CURSOR FOR `+query, args...); err != nil {
+			return mapError(err)
+		}
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM persons_export", exportFetchSize)
+		for {
+			n, err := fetchPersons(ctx, tx, fetch, fn)
+			if err != nil {
+				return err
+			}
+			if n < exportFetchSize {
+				return nil
+			}
+		}
+	})
+}
+
+func fetchPersons(ctx context.Context, tx *sql.Tx, fetch string, fn func(models.Person) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, mapError(err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			return n, err
+		}
+		n++
+		if err := fn(*p); err != nil {
+			return n, err
+		}
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+)
+
+const historyColumns = `id, person_id, action, changed_by, changed_at, before, after`
+
+// insertHistory пишет запись истории в той же транзакции, что и изменение.
+func insertHistory(ctx context.Context, tx *sql.Tx, personID uuid.UUID, action string, before, after *models.Person) error {
+	beforeJSON, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO person_history (person_id, action, changed_by, before, after)
+		VALUES ($1, $2, $3, $4, $5)`,
+		personID, action, actor.FromContext(ctx), beforeJSON, afterJSON)
+	return mapError(err)
+}
+
+func marshalSnapshot(p *models.Person) ([]byte, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (r *PersonRepository) History(ctx context.Context, personID uuid.UUID, limit, offset int) ([]models.PersonHistoryEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+historyColumns+` FROM person_history
+		WHERE person_id = $1
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2 OFFSET $3`, personID, limit, offset)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	entries := []models.PersonHistoryEntry{}
+	for rows.Next() {
+		e, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *PersonRepository) GetAsOf(ctx context.Context, personID uuid.UUID, at time.Time) (*models.Person, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+historyColumns+` FROM person_history
+		WHERE person_id = $1 AND changed_at <= $2
+		ORDER BY changed_at DESC, id DESC
+		LIMIT 1`, personID, at)
+	e, err := scanHistoryEntry(row)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if e.After == nil {
+		return nil, ErrNotFound
+	}
+	return e.After, nil
+}
+
+func scanHistoryEntry(row scanner) (*models.PersonHistoryEntry, error) {
+	var e models.PersonHistoryEntry
+	var before, after []byte
+	if err := row.Scan(&e.ID, &e.PersonID, &e.Action, &e.ChangedBy, &e.ChangedAt, &before, &after); err != nil {
+		return nil, err
+	}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &e.Before); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &e.After); err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
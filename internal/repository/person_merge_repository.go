@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SimilarPairs соединяет таблицу саму с собой по триграммному индексу name_key.
+func (r *PersonRepository) SimilarPairs(ctx context.Context, threshold float64, limit int) ([]SimilarPair, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, b.id, similarity(a.name_key, b.name_key) AS score
+		FROM persons a
+		JOIN persons b ON a.id < b.id AND a.name_key % b.name_key
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND similarity(a.name_key, b.name_key) >= $1
+		ORDER BY score DESC, a.id, b.id
+		LIMIT $2`, threshold, limit)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var pairs []SimilarPair
+	for rows.Next() {
+		var p SimilarPair
+		if err := rows.Scan(&p.A, &p.B, &p.Similarity); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+func (r *PersonRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Person, error) {
+	return r.queryPersons(ctx, `
+		SELECT `+personColumns+` FROM persons
+		WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL`, pq.Array(uuidStrings(ids)))
+}
+
+func (r *PersonRepository) Merge(ctx context.Context, survivorID uuid.UUID, mergedIDs []uuid.UUID, resolve MergeFunc) (*models.Person, error) {
+	var survivor *models.Person
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		// блокировка в порядке id, чтобы встречные слияния не взаимоблокировались
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+personColumns+` FROM persons
+			WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL
+			ORDER BY id
+			FOR UPDATE`, pq.Array(uuidStrings(append([]uuid.UUID{survivorID}, mergedIDs...))))
+		if err != nil {
+			return mapError(err)
+		}
+		locked := map[uuid.UUID]*models.Person{}
+		for rows.Next() {
+			p, err := scanPerson(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			locked[p.ID] = p
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return mapError(err)
+		}
+
+		before, merged, err := mergeParticipants(locked, survivorID, mergedIDs)
+		if err != nil {
+			return err
+		}
+		result, err := resolve(*before, merged)
+		if err != nil {
+			return err
+		}
+
+		// поглощаемые удаляются раньше обновления выжившего, иначе он не сможет занять их ФИО
+		for i := range merged {
+			deleted, err := scanPerson(tx.QueryRowContext(ctx, `
+				UPDATE persons SET deleted_at = NOW(), updated_at = NOW(), version = version + 1
+				WHERE id = $1
+				RETURNING `+personColumns, merged[i].ID))
+			if err != nil {
+				return mapError(err)
+			}
+			if err := insertHistory(ctx, tx, deleted.ID, models.HistoryMerge, &merged[i], deleted); err != nil {
+				return err
+			}
+		}
+
+		ids := pq.Array(uuidStrings(mergedIDs))
+		// перенаправления на поглощаемых переводятся на выжившего, чтобы не было цепочек
+		if _, err := tx.ExecContext(ctx, `UPDATE person_merges SET survivor_id = $1 WHERE survivor_id = ANY($2::uuid[])`, survivorID, ids); err != nil {
+			return mapError(err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO person_merges (merged_id, survivor_id, merged_by)
+			SELECT unnest($2::uuid[]), $1, $3
+			ON CONFLICT (merged_id) DO UPDATE SET survivor_id = EXCLUDED.survivor_id, merged_by = EXCLUDED.merged_by, merged_at = NOW()`,
+			survivorID, ids, actor.FromContext(ctx)); err != nil {
+			return mapError(err)
+		}
+
+		survivor, err = scanPerson(tx.QueryRowContext(ctx, `
+			UPDATE persons SET name = $2, surname = $3, patronymic = $4, age = $5, birth_year = $6, gender = $7, nationality = $8,
+				name_original = $9, surname_original = $10, patronymic_original = $11, updated_at = NOW(), version = version + 1
+			WHERE id = $1
+			RETURNING `+personColumns,
+			survivorID, result.Name, result.Surname, result.Patronymic, result.Age, nullableInt(result.BirthYear), result.Gender, result.Nationality,
+			result.NameOriginal, result.SurnameOriginal, result.PatronymicOriginal))
+		if err != nil {
+			return mapError(err)
+		}
+		return insertHistory(ctx, tx, survivorID, models.HistoryMerge, before, survivor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return survivor, nil
+}
+
+func (r *PersonRepository) MergedInto(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	var survivorID uuid.UUID
+	err := r.db.QueryRowContext(ctx, `SELECT survivor_id FROM person_merges WHERE merged_id = $1`, id).Scan(&survivorID)
+	if err != nil {
+		return uuid.Nil, mapError(err)
+	}
+	return survivorID, nil
+}
+
+// mergeParticipants раскладывает заблокированные записи на выжившего и поглощаемых в порядке mergedIDs.
+func mergeParticipants(locked map[uuid.UUID]*models.Person, survivorID uuid.UUID, mergedIDs []uuid.UUID) (*models.Person, []models.Person, error) {
+	survivor, ok := locked[survivorID]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	merged := make([]models.Person, 0, len(mergedIDs))
+	for _, id := range mergedIDs {
+		p, ok := locked[id]
+		if !ok {
+			return nil, nil, ErrNotFound
+		}
+		merged = append(merged, *p)
+	}
+	return survivor, merged, nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	return s
+}
@@ -3,15 +3,22 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/filter"
 	"effective-mobile-task/internal/models"
 	"github.com/google/uuid"
 )
 
+const personColumns = `id, name, surname, patronymic, age, birth_year, gender, nationality, enrichment_sources, created_at, updated_at, version, deleted_at,
+	name_original, surname_original, patronymic_original`
+
 type PersonRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 func NewPersonRepository(db *sql.DB) *PersonRepository {
@@ -19,14 +26,69 @@ func NewPersonRepository(db *sql.DB) *PersonRepository {
 }
 
 func (r *PersonRepository) Create(ctx context.Context, p models.Person) error {
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO persons (id, name, surname, patronymic, age, gender, nationality)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		p.ID, p.Name, p.Surname, p.Patronymic, p.Age, p.Gender, p.Nationality)
-	return err
+	sources, err := json.Marshal(p.EnrichmentSources)
+	if err != nil {
+		return err
+	}
+
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		created, err := scanPerson(tx.QueryRowContext(ctx, `
+			INSERT INTO persons (id, name, surname, patronymic, age, birth_year, gender, nationality, enrichment_sources, version,
+				name_original, surname_original, patronymic_original)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			RETURNING `+personColumns,
+			p.ID, p.Name, p.Surname, p.Patronymic, p.Age, nullableInt(p.BirthYear), p.Gender, p.Nationality, sources, p.Version,
+			p.NameOriginal, p.SurnameOriginal, p.PatronymicOriginal))
+		if err != nil {
+			return mapError(err)
+		}
+		return insertHistory(ctx, tx, p.ID, models.HistoryCreate, nil, created)
+	})
+}
+
+// bulkInsertChunk ограничивает число строк в одном INSERT, чтобы не упереться в лимит параметров.
+const bulkInsertChunk = 500
+
+// CreateMany вставляет людей многострочными INSERT, записывая историю тем же запросом.
+func (r *PersonRepository) CreateMany(ctx context.Context, people []models.Person) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		for start := 0; start < len(people); start += bulkInsertChunk {
+			chunk := people[start:min(start+bulkInsertChunk, len(people))]
+
+			values := make([]string, 0, len(chunk))
+			args := make([]interface{}, 0, len(chunk)*13+2)
+			for _, p := range chunk {
+				sources, err := json.Marshal(p.EnrichmentSources)
+				if err != nil {
+					return err
+				}
+				n := len(args)
+				values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+					n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13))
+				args = append(args, p.ID, p.Name, p.Surname, p.Patronymic, p.Age, nullableInt(p.BirthYear), p.Gender, p.Nationality, sources, p.Version,
+					p.NameOriginal, p.SurnameOriginal, p.PatronymicOriginal)
+			}
+			args = append(args, models.HistoryCreate, actor.FromContext(ctx))
+
+			_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+				WITH created AS (
+					INSERT INTO persons (id, name, surname, patronymic, age, birth_year, gender, nationality, enrichment_sources, version,
+						name_original, surname_original, patronymic_original)
+					VALUES %s
+					RETURNING *
+				)
+				INSERT INTO person_history (person_id, action, changed_by, after)
+				SELECT id, $%d, $%d, to_jsonb(created) FROM created`,
+				strings.Join(values, ", "), len(args)-1, len(args)), args...)
+			if err != nil {
+				return mapError(err)
+			}
+		}
+		return nil
+	})
 }
 
-func (r *PersonRepository) Update(ctx context.Context, id uuid.UUID, update models.UpdatePersonRequest) error {
+func (r *PersonRepository) Update(ctx context.Context, id uuid.UUID, version int, update models.UpdatePersonRequest) (*models.Person, error) {
 	setParts := []string{}
 	args := []interface{}{}
 	argPos := 1
@@ -46,99 +108,357 @@ func (r *PersonRepository) Update(ctx context.Context, id uuid.UUID, update mode
 		args = append(args, *update.Patronymic)
 		argPos++
 	}
-
-	if len(setParts) == 0 {
-		return nil
+	originals := []struct {
+		column string
+		value  *string
+	}{
+		{"name_original", update.NameOriginal},
+		{"surname_original", update.SurnameOriginal},
+		{"patronymic_original", update.PatronymicOriginal},
+	}
+	for _, o := range originals {
+		if o.value != nil {
+			setParts = append(setParts, fmt.Sprintf("%s = $%d", o.column, argPos))
+			args = append(args, *o.value)
+			argPos++
+		}
 	}
 
-	setParts = append(setParts, "updated_at = NOW()")
+	var updated *models.Person
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		before, err := lockPerson(ctx, tx, id, version, false)
+		if err != nil {
+			return err
+		}
+		if len(setParts) == 0 {
+			updated = before
+			return nil
+		}
 
-	query := fmt.Sprintf("UPDATE persons SET %s WHERE id = $%d", strings.Join(setParts, ", "), argPos)
-	args = append(args, id)
+		setParts = append(setParts, "updated_at = NOW()", "version = version + 1")
+		query := fmt.Sprintf("UPDATE persons SET %s WHERE id = $%d RETURNING %s", strings.Join(setParts, ", "), argPos, personColumns)
+		args = append(args, id)
 
-	_, err := r.db.ExecContext(ctx, query, args...)
-	return err
+		updated, err = scanPerson(tx.QueryRowContext(ctx, query, args...))
+		if err != nil {
+			return mapError(err)
+		}
+		return insertHistory(ctx, tx, id, models.HistoryUpdate, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
-func (r *PersonRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM persons WHERE id = $1", id)
-	return err
+func (r *PersonRepository) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		before, err := lockPerson(ctx, tx, id, version, false)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := scanPerson(tx.QueryRowContext(ctx, `
+			UPDATE persons SET deleted_at = NOW(), version = version + 1
+			WHERE id = $1
+			RETURNING `+personColumns, id))
+		if err != nil {
+			return mapError(err)
+		}
+		return insertHistory(ctx, tx, id, models.HistoryDelete, before, deleted)
+	})
 }
 
-func (r *PersonRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Person, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT id, name, surname, patronymic, age, gender, nationality, created_at, updated_at
-		FROM persons WHERE id = $1`, id)
+func (r *PersonRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Person, error) {
+	var restored *models.Person
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		before, err := lockPerson(ctx, tx, id, 0, true)
+		if err != nil {
+			return err
+		}
+		if before.DeletedAt == nil {
+			return ErrNotDeleted
+		}
 
-	var p models.Person
-	err := row.Scan(&p.ID, &p.Name, &p.Surname, &p.Patronymic, &p.Age, &p.Gender, &p.Nationality, &p.CreatedAt, &p.UpdatedAt)
+		restored, err = scanPerson(tx.QueryRowContext(ctx, `
+			UPDATE persons SET deleted_at = NULL, updated_at = NOW(), version = version + 1
+			WHERE id = $1
+			RETURNING `+personColumns, id))
+		if err != nil {
+			return mapError(err)
+		}
+		return insertHistory(ctx, tx, id, models.HistoryRestore, before, restored)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &p, nil
+	return restored, nil
 }
 
-func (r *PersonRepository) GetAll(ctx context.Context, limit, offset int, filters map[string]string) ([]models.Person, error) {
-	query := `SELECT id, name, surname, patronymic, age, gender, nationality, created_at, updated_at
-			  FROM persons WHERE 1=1`
-	args := []interface{}{}
-	argID := 1
-
-	if name, ok := filters["name"]; ok {
-		query += fmt.Sprintf(" AND name ILIKE $%d", argID)
-		args = append(args, "%"+name+"%")
-		argID++
-	}
-	if surname, ok := filters["surname"]; ok {
-		query += fmt.Sprintf(" AND surname ILIKE $%d", argID)
-		args = append(args, "%"+surname+"%")
-		argID++
-	}
-	if gender, ok := filters["gender"]; ok {
-		query += fmt.Sprintf(" AND gender = $%d", argID)
-		args = append(args, gender)
-		argID++
-	}
-	if nationality, ok := filters["nationality"]; ok {
-		query += fmt.Sprintf(" AND nationality = $%d", argID)
-		args = append(args, nationality)
-		argID++
-	}
-	if age, ok := filters["age"]; ok {
-		query += fmt.Sprintf(" AND age = $%d", argID)
-		args = append(args, age)
-		argID++
-	}
-	//фильтрация по возрасту возраст больше чем
-	if ageGT, ok := filters["age_gt"]; ok {
-		query += fmt.Sprintf(" AND age > $%d", argID)
-		args = append(args, ageGT)
-		argID++
-	}
-	//фильтрация по возрасту возраст меньше чем
-	if ageLT, ok := filters["age_lt"]; ok {
-		query += fmt.Sprintf(" AND age < $%d", argID)
-		args = append(args, ageLT)
-		argID++
-	}
-
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argID, argID+1)
+// Purge окончательно удаляет записи, сохраняя их последнее состояние в истории.
+func (r *PersonRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		WITH purged AS (
+			DELETE FROM persons WHERE deleted_at < $1 RETURNING *
+		)
+		INSERT INTO person_history (person_id, action, changed_by, before)
+		SELECT id, $2, $3, to_jsonb(purged) FROM purged`,
+		deletedBefore, models.HistoryPurge, actor.FromContext(ctx))
+	if err != nil {
+		return 0, mapError(err)
+	}
+	return res.RowsAffected()
+}
+
+// lockPerson блокирует строку до конца транзакции и проверяет ожидаемую версию,
+// так что проверка и последующее изменение выполняются атомарно.
+func lockPerson(ctx context.Context, tx *sql.Tx, id uuid.UUID, version int, includeDeleted bool) (*models.Person, error) {
+	query := `SELECT ` + personColumns + ` FROM persons WHERE id = $1`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+	query += ` FOR UPDATE`
+
+	p, err := scanPerson(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if version > 0 && p.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return p, nil
+}
+
+func (r *PersonRepository) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*models.Person, error) {
+	query := `SELECT ` + personColumns + ` FROM persons WHERE id = $1`
+	if !includeDeleted {
+		query += ` AND deleted_at IS NULL`
+	}
+	row := r.db.QueryRowContext(ctx, query, id)
+	p, err := scanPerson(row)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return p, nil
+}
+
+func (r *PersonRepository) GetAll(ctx context.Context, limit, offset int, f filter.Filter, sort Sort) ([]models.Person, error) {
+	where, args := filterConditions(f)
+	argID := len(args) + 1
+
+	query := `SELECT ` + personColumns + ` FROM persons WHERE 1=1` + where + sort.orderBy(false)
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argID, argID+1)
 	args = append(args, limit, offset)
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	return r.queryPersons(ctx, query, args...)
+}
+
+func (r *PersonRepository) GetPage(ctx context.Context, limit int, cursor *Cursor, f filter.Filter, sort Sort) (*Page, error) {
+	where, args := filterConditions(f)
+
+	query := `SELECT ` + personColumns + ` FROM persons WHERE 1=1` + where
+	backward := false
+	if cursor != nil {
+		values, err := sort.cursorValues(cursor)
+		if err != nil {
+			return nil, err
+		}
+		backward = cursor.Backward
+		cond, condArgs := sort.after(values, backward, len(args)+1)
+		query += cond
+		args = append(args, condArgs...)
+	}
+	query += sort.orderBy(backward) + fmt.Sprintf(" LIMIT $%d", len(args)+1)
+	args = append(args, limit+1)
+
+	people, err := r.queryPersons(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	return newPage(people, limit, sort, cursor), nil
+}
+
+func (r *PersonRepository) Count(ctx context.Context, f filter.Filter, estimate bool) (int64, bool, error) {
+	// без фильтров берется размер таблицы из pg_class (с удаленными записями),
+	// с фильтрами - оценка строк из плана запроса; reltuples < 0 - таблица еще не анализировалась
+	if estimate && len(f.Conditions) == 0 {
+		var reltuples float64
+		err := r.db.QueryRowContext(ctx, `SELECT reltuples FROM pg_class WHERE oid = 'persons'::regclass`).Scan(&reltuples)
+		if err != nil {
+			return 0, false, mapError(err)
+		}
+		if reltuples >= 0 {
+			return int64(reltuples), true, nil
+		}
+	}
+
+	where, args := filterConditions(f)
+	if estimate && len(f.Conditions) > 0 {
+		var plan []byte
+		err := r.db.QueryRowContext(ctx, `EXPLAIN (FORMAT JSON) SELECT 1 FROM persons WHERE 1=1`+where, args...).Scan(&plan)
+		if err != nil {
+			return 0, false, mapError(err)
+		}
+		var explain []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal(plan, &explain); err != nil {
+			return 0, false, err
+		}
+		if len(explain) > 0 {
+			return int64(explain[0].Plan.Rows), true, nil
+		}
+	}
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM persons WHERE 1=1`+where, args...).Scan(&total)
+	if err != nil {
+		return 0, false, mapError(err)
+	}
+	return total, false, nil
+}
+
+// filterConditions строит условия WHERE для фильтра списка в виде " AND ..." с аргументами $1..$n.
+func filterConditions(f filter.Filter) (string, []interface{}) {
+	var query string
+	var args []interface{}
+
+	if !f.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+
+	// возраст считается на текущий год по оценочному году рождения
+	currentYear := time.Now().Year()
+	for _, c := range f.Conditions {
+		column, values, op := c.Field, c.Values, c.Op
+		switch c.Field {
+		case "patronymic":
+			column = "COALESCE(patronymic, '')"
+		case "age":
+			// возраст растет при убывании года рождения, поэтому границы меняются местами
+			column, op = "birth_year", invertOp(op)
+			values = make([]interface{}, len(c.Values))
+			for i, v := range c.Values {
+				values[len(values)-1-i] = currentYear - v.(int)
+			}
+		}
+
+		arg := func(v interface{}) string {
+			args = append(args, v)
+			return fmt.Sprintf("$%d", len(args))
+		}
+		var cond string
+		switch op {
+		case filter.OpNull:
+			cond = column + " = ''"
+			if !values[0].(bool) {
+				cond = column + " <> ''"
+			}
+		case filter.OpGt, filter.OpGte, filter.OpLt, filter.OpLte:
+			cond = column + " " + sqlOps[op] + " " + arg(values[0])
+		case filter.OpBetween:
+			cond = column + " BETWEEN " + arg(values[0]) + " AND " + arg(values[1])
+		case filter.OpRange:
+			cond = column + " >= " + arg(values[0]) + " AND " + column + " < " + arg(values[1])
+		default:
+			var ors []string
+			for _, v := range values {
+				ors = append(ors, matchCondition(column, filter.KindOf(c.Field), op, arg, v))
+			}
+			cond = strings.Join(ors, " OR ")
+		}
+
+		if c.Not {
+			query += " AND NOT (" + cond + ")"
+		} else {
+			query += " AND (" + cond + ")"
+		}
+	}
+
+	return query, args
+}
+
+var sqlOps = map[filter.Op]string{filter.OpGt: ">", filter.OpGte: ">=", filter.OpLt: "<", filter.OpLte: "<="}
+
+// matchCondition сравнивает колонку с одним значением: текст без учета регистра и с
+// экранированием спецсимволов LIKE, остальные типы - на равенство.
+func matchCondition(column string, kind filter.Kind, op filter.Op, arg func(interface{}) string, v interface{}) string {
+	if kind != filter.KindText {
+		return column + " = " + arg(v)
+	}
+	pattern := likeEscaper.Replace(v.(string))
+	switch op {
+	case filter.OpPrefix:
+		pattern += "%"
+	case filter.OpContains:
+		pattern = "%" + pattern + "%"
+	}
+	return column + " ILIKE " + arg(pattern)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func invertOp(op filter.Op) filter.Op {
+	switch op {
+	case filter.OpGt:
+		return filter.OpLt
+	case filter.OpGte:
+		return filter.OpLte
+	case filter.OpLt:
+		return filter.OpGt
+	case filter.OpLte:
+		return filter.OpGte
+	}
+	return op
+}
+
+func (r *PersonRepository) queryPersons(ctx context.Context, query string, args ...interface{}) ([]models.Person, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
 	defer rows.Close()
 
 	var people []models.Person
 	for rows.Next() {
-		var p models.Person
-		if err := rows.Scan(&p.ID, &p.Name, &p.Surname, &p.Patronymic, &p.Age, &p.Gender, &p.Nationality, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		p, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, *p)
+	}
+
+	return people, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPerson(row scanner) (*models.Person, error) {
+	var p models.Person
+	var birthYear sql.NullInt64
+	var deletedAt sql.NullTime
+	var sources []byte
+	err := row.Scan(&p.ID, &p.Name, &p.Surname, &p.Patronymic, &p.Age, &birthYear, &p.Gender, &p.Nationality, &sources, &p.CreatedAt, &p.UpdatedAt, &p.Version, &deletedAt,
+		&p.NameOriginal, &p.SurnameOriginal, &p.PatronymicOriginal)
+	if err != nil {
+		return nil, err
+	}
+	p.BirthYear = int(birthYear.Int64)
+	if deletedAt.Valid {
+		p.DeletedAt = &deletedAt.Time
+	}
+	if len(sources) > 0 {
+		if err := json.Unmarshal(sources, &p.EnrichmentSources); err != nil {
 			return nil, err
 		}
-		people = append(people, p)
 	}
+	return &p, nil
+}
 
-	return people, nil
+func nullableInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
 }
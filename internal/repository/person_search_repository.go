@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/search"
+)
+
+// Search отбирает кандидатов по tsvector (точные слова) и триграммам (опечатки) через GIN-индексы,
+// а ранжирует по среднему по словам запроса лучшему сходству с именем, фамилией или отчеством
+// плюс ts_rank, чтобы точные совпадения шли выше похожих.
+func (r *PersonRepository) Search(ctx context.Context, query string, limit int) ([]models.PersonSearchResult, error) {
+	tokens := search.Tokens(query)
+	if len(tokens) == 0 {
+		return []models.PersonSearchResult{}, nil
+	}
+
+	args := []interface{}{strings.Join(tokens, " ")}
+	var similar, best []string
+	for _, t := range tokens {
+		args = append(args, t)
+		n := len(args)
+		similar = append(similar, fmt.Sprintf("name %% $%d OR surname %% $%d OR patronymic %% $%d", n, n, n))
+		best = append(best, fmt.Sprintf("GREATEST(similarity(name, $%d), similarity(surname, $%d), similarity(COALESCE(patronymic, ''), $%d))", n, n, n))
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s, score FROM (
+			SELECT *, (%s) / %d + ts_rank(search_vector, plainto_tsquery('simple', $1)) AS score
+			FROM persons
+			WHERE deleted_at IS NULL
+				AND (search_vector @@ plainto_tsquery('simple', $1) OR %s)
+		) matches
+		ORDER BY score DESC, id
+		LIMIT $%d`,
+		personColumns, strings.Join(best, " + "), len(tokens), strings.Join(similar, " OR "), len(args)), args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	results := []models.PersonSearchResult{}
+	for rows.Next() {
+		var score float64
+		p, err := scanPerson(scannerWith{rows, &score})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.PersonSearchResult{Person: *p, Score: score})
+	}
+	return results, rows.Err()
+}
+
+// scannerWith дописывает к колонкам человека дополнительные колонки запроса.
+type scannerWith struct {
+	row   scanner
+	extra interface{}
+}
+
+func (s scannerWith) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra)...)
+}
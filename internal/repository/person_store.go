@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+)
+
+// PersonStore - хранилище людей. Реализации: PersonRepository (Postgres) и MemoryPersonRepository.
+// Update и Delete с version > 0 выполняются, только если текущая версия записи совпадает,
+// иначе возвращается ErrVersionMismatch. Update, Delete и Restore увеличивают версию.
+// Delete только помечает запись удаленной: такие записи не видны остальным методам,
+// пока не переданы includeDeleted или filter.Filter.IncludeDeleted, и окончательно удаляются в Purge.
+// Каждое изменение записывается в историю вместе с автором из actor.FromContext.
+// Два неудаленных человека не могут иметь одинаковый NameKey: такие Create, CreateMany,
+// Update и Restore возвращают ErrConflict.
+type PersonStore interface {
+	Create(ctx context.Context, p models.Person) error
+	// CreateMany сохраняет всех людей в одной транзакции: при ошибке не сохраняется никто.
+	CreateMany(ctx context.Context, people []models.Person) error
+	// FindDuplicate возвращает неудаленного человека с тем же NameKey, а при threshold > 0 -
+	// самого похожего по триграммам NameKey, если сходство не ниже threshold. Нет такого - ErrNotFound.
+	FindDuplicate(ctx context.Context, req models.CreatePersonRequest, threshold float64) (*models.Person, error)
+	// SimilarPairs возвращает до limit пар неудаленных людей со сходством NameKey не ниже threshold,
+	// самые похожие первыми.
+	SimilarPairs(ctx context.Context, threshold float64, limit int) ([]SimilarPair, error)
+	// GetByIDs возвращает неудаленных людей из ids в произвольном порядке, отсутствующие пропускаются.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Person, error)
+	// Merge в одной транзакции блокирует выжившего и поглощаемых, получает новое состояние выжившего
+	// от resolve, удаляет поглощаемых и запоминает перенаправления с них на выжившего.
+	// Если кого-то нет среди неудаленных, возвращается ErrNotFound; ошибка resolve возвращается как есть.
+	Merge(ctx context.Context, survivorID uuid.UUID, mergedIDs []uuid.UUID, resolve MergeFunc) (*models.Person, error)
+	// MergedInto возвращает id выжившего для поглощенного при слиянии человека, иначе ErrNotFound.
+	MergedInto(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
+	Update(ctx context.Context, id uuid.UUID, version int, update models.UpdatePersonRequest) (*models.Person, error)
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+	Restore(ctx context.Context, id uuid.UUID) (*models.Person, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*models.Person, error)
+	GetAll(ctx context.Context, limit, offset int, f filter.Filter, sort Sort) ([]models.Person, error)
+	// GetPage - keyset-пагинация: страница после курсора (или перед ним, если cursor.Backward),
+	// nil - первая страница. Курсор, выданный для другой сортировки, дает ErrInvalidCursor.
+	GetPage(ctx context.Context, limit int, cursor *Cursor, f filter.Filter, sort Sort) (*Page, error)
+	// Export по очереди передает fn людей под фильтром в порядке sort, не загружая весь список в память.
+	// Ошибка fn прерывает выгрузку и возвращается как есть.
+	Export(ctx context.Context, f filter.Filter, sort Sort, fn func(models.Person) error) error
+	// Count считает записи под фильтрами. С estimate реализация может вернуть
+	// приблизительное значение, тогда estimated = true.
+	Count(ctx context.Context, f filter.Filter, estimate bool) (total int64, estimated bool, err error)
+	// Search ищет неудаленных людей по ФИО с учетом опечаток, лучшие совпадения первыми.
+	// Highlights не заполняются.
+	Search(ctx context.Context, query string, limit int) ([]models.PersonSearchResult, error)
+	History(ctx context.Context, personID uuid.UUID, limit, offset int) ([]models.PersonHistoryEntry, error)
+	// GetAsOf возвращает состояние человека на момент at по истории изменений.
+	GetAsOf(ctx context.Context, personID uuid.UUID, at time.Time) (*models.Person, error)
+}
+
+// SimilarPair - два человека с похожими ФИО, A < B.
+type SimilarPair struct {
+	A, B       uuid.UUID
+	Similarity float64
+}
+
+// MergeFunc возвращает выжившего после слияния; merged идут в порядке запроса.
+// Из результата сохраняются ФИО, возраст, год рождения, пол и национальность.
+type MergeFunc func(survivor models.Person, merged []models.Person) (models.Person, error)
+
+var (
+	_ PersonStore = (*PersonRepository)(nil)
+	_ PersonStore = (*MemoryPersonRepository)(nil)
+)
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"effective-mobile-task/internal/models"
+)
+
+type sortKind int
+
+const (
+	sortText sortKind = iota
+	sortInt
+	sortTime
+)
+
+type sortColumn struct {
+	// expr не содержит NULL, иначе сравнение с курсором теряло бы записи
+	expr string
+	kind sortKind
+	// inverted - выражение растет в обратную сторону от поля (возраст и год рождения)
+	inverted bool
+	value    func(p models.Person) interface{}
+}
+
+// sortColumns - поля, по которым разрешена сортировка списка.
+var sortColumns = map[string]sortColumn{
+	"name":        {expr: "name", kind: sortText, value: func(p models.Person) interface{} { return p.Name }},
+	"surname":     {expr: "surname", kind: sortText, value: func(p models.Person) interface{} { return p.Surname }},
+	"patronymic":  {expr: "COALESCE(patronymic, '')", kind: sortText, value: func(p models.Person) interface{} { return p.Patronymic }},
+	"gender":      {expr: "gender", kind: sortText, value: func(p models.Person) interface{} { return p.Gender }},
+	"nationality": {expr: "nationality", kind: sortText, value: func(p models.Person) interface{} { return p.Nationality }},
+	"age":         {expr: "COALESCE(birth_year, 0)", kind: sortInt, inverted: true, value: func(p models.Person) interface{} { return p.BirthYear }},
+	"created_at":  {expr: "created_at", kind: sortTime, value: func(p models.Person) interface{} { return p.CreatedAt }},
+	"updated_at":  {expr: "updated_at", kind: sortTime, value: func(p models.Person) interface{} { return p.UpdatedAt }},
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Sort - порядок списка. Последним ключом всегда неявно идет id в направлении первого поля,
+// так что порядок однозначен и пригоден для курсоров.
+type Sort []SortField
+
+// DefaultSort - порядок по умолчанию: сначала новые.
+var DefaultSort = Sort{{Field: "created_at", Desc: true}}
+
+// ParseSort разбирает параметр вида "-age,surname": минус означает убывание.
+func ParseSort(s string) (Sort, error) {
+	if s == "" {
+		return DefaultSort, nil
+	}
+
+	var sort Sort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		f := SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(f.Field, "-") {
+			f.Field, f.Desc = f.Field[1:], true
+		}
+		if _, ok := sortColumns[f.Field]; !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidSort, f.Field)
+		}
+		if seen[f.Field] {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, f.Field)
+		}
+		seen[f.Field] = true
+		sort = append(sort, f)
+	}
+	return sort, nil
+}
+
+func (s Sort) String() string {
+	parts := make([]string, len(s))
+	for i, f := range s {
+		parts[i] = f.Field
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+type sortKey struct {
+	expr string
+	kind sortKind
+	desc bool
+}
+
+// keys раскрывает порядок в направления SQL-выражений, включая id.
+func (s Sort) keys() []sortKey {
+	keys := make([]sortKey, 0, len(s)+1)
+	for _, f := range s {
+		col := sortColumns[f.Field]
+		keys = append(keys, sortKey{expr: col.expr, kind: col.kind, desc: f.Desc != col.inverted})
+	}
+	return append(keys, sortKey{expr: "id", kind: sortText, desc: s[0].Desc})
+}
+
+// values возвращает значения ключей сортировки записи, включая id.
+func (s Sort) values(p models.Person) []interface{} {
+	values := make([]interface{}, 0, len(s)+1)
+	for _, f := range s {
+		values = append(values, sortColumns[f.Field].value(p))
+	}
+	return append(values, p.ID.String())
+}
+
+// orderBy строит ORDER BY; backward переворачивает все направления.
+func (s Sort) orderBy(backward bool) string {
+	keys := s.keys()
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.desc != backward {
+			dir = "DESC"
+		}
+		parts[i] = k.expr + " " + dir
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// after строит условие "запись дальше курсора в порядке списка" (или раньше, если backward)
+// в виде (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., так как направления ключей могут различаться.
+func (s Sort) after(values []interface{}, backward bool, argID int) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, k := range s.keys() {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = $%d", s.keys()[j].expr, argID+j))
+		}
+		op := ">"
+		if k.desc != backward {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s $%d", k.expr, op, argID+i))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		args = append(args, values[i])
+	}
+	return " AND (" + strings.Join(ors, " OR ") + ")", args
+}
+
+// compare сравнивает записи в порядке списка.
+func (s Sort) compare(a, b []interface{}) int {
+	for i, k := range s.keys() {
+		var c int
+		switch k.kind {
+		case sortText:
+			c = cmp.Compare(a[i].(string), b[i].(string))
+		case sortInt:
+			c = cmp.Compare(a[i].(int), b[i].(int))
+		case sortTime:
+			c = a[i].(time.Time).Compare(b[i].(time.Time))
+		}
+		if k.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func formatSortValue(kind sortKind, v interface{}) string {
+	switch kind {
+	case sortInt:
+		return strconv.Itoa(v.(int))
+	case sortTime:
+		return v.(time.Time).Format(time.RFC3339Nano)
+	}
+	return v.(string)
+}
+
+func parseSortValue(kind sortKind, s string) (interface{}, error) {
+	switch kind {
+	case sortInt:
+		return strconv.Atoi(s)
+	case sortTime:
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	return s, nil
+}
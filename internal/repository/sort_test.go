@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Sort
+		wantErr error
+	}{
+		{in: "", want: DefaultSort},
+		{in: "name", want: Sort{{Field: "name"}}},
+		{in: "-age,surname", want: Sort{{Field: "age", Desc: true}, {Field: "surname"}}},
+		{in: " surname , -created_at", want: Sort{{Field: "surname"}, {Field: "created_at", Desc: true}}},
+		{in: "id", wantErr: ErrInvalidSort},
+		{in: "name,-name", wantErr: ErrInvalidSort},
+		{in: "name,", wantErr: ErrInvalidSort},
+		{in: "--name", wantErr: ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSort(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseSort(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSort(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSortStringRoundTrip(t *testing.T) {
+	for _, in := range []string{"name", "-age,surname", "gender,-nationality,updated_at"} {
+		s, err := ParseSort(in)
+		if err != nil {
+			t.Fatalf("ParseSort(%q): %v", in, err)
+		}
+		if s.String() != in {
+			t.Errorf("String() = %q, want %q", s.String(), in)
+		}
+	}
+}
+
+func TestSortOrderBy(t *testing.T) {
+	s := Sort{{Field: "age", Desc: true}, {Field: "surname"}}
+
+	// возраст растет в обратную сторону от года рождения, id идет в направлении первого поля
+	if got, want := s.orderBy(false), " ORDER BY COALESCE(birth_year, 0) ASC, surname ASC, id DESC"; got != want {
+		t.Errorf("orderBy(false) = %q, want %q", got, want)
+	}
+	if got, want := s.orderBy(true), " ORDER BY COALESCE(birth_year, 0) DESC, surname DESC, id ASC"; got != want {
+		t.Errorf("orderBy(true) = %q, want %q", got, want)
+	}
+}
+
+func TestSortAfter(t *testing.T) {
+	s := Sort{{Field: "surname"}, {Field: "created_at", Desc: true}}
+	values := []interface{}{"Ivanov", time.Unix(0, 0), "id"}
+
+	got, args := s.after(values, false, 3)
+	want := " AND ((surname > $3) OR (surname = $3 AND created_at < $4) OR (surname = $3 AND created_at = $4 AND id > $5))"
+	if got != want {
+		t.Errorf("after = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(args, values) {
+		t.Errorf("args = %v, want %v", args, values)
+	}
+
+	got, _ = s.after(values, true, 1)
+	want = " AND ((surname < $1) OR (surname = $1 AND created_at > $2) OR (surname = $1 AND created_at = $2 AND id < $3))"
+	if got != want {
+		t.Errorf("after backward = %q, want %q", got, want)
+	}
+}
+
+func TestSortCompare(t *testing.T) {
+	s := Sort{{Field: "age", Desc: true}, {Field: "name"}}
+	older := []interface{}{1980, "Anna", "b"}
+	younger := []interface{}{2000, "Anna", "a"}
+	sameAge := []interface{}{1980, "Boris", "c"}
+
+	if s.compare(older, younger) >= 0 {
+		t.Error("older person must come first with -age")
+	}
+	if s.compare(older, sameAge) >= 0 {
+		t.Error("equal age must be ordered by name")
+	}
+	if s.compare(older, older) != 0 {
+		t.Error("equal values must compare as 0")
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DBTX - методы, общие у *sql.DB и *sql.Tx. Репозитории выполняют запросы через пул
+// или, внутри UnitOfWork, через транзакцию.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var (
+	_ DBTX = (*sql.DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
+
+// Stores - хранилища, привязанные к одной транзакции.
+type Stores struct {
+	Persons     PersonStore
+	Imports     ImportStore
+	Idempotency IdempotencyStore
+}
+
+// UnitOfWork выполняет несколько вызовов хранилищ атомарно. Реализации: TxManager (Postgres)
+// и MemoryUnitOfWork.
+type UnitOfWork interface {
+	// WithTx вызывает fn с хранилищами, привязанными к новой транзакции, и фиксирует ее,
+	// если fn вернула nil, иначе откатывает. После конфликта сериализации или взаимоблокировки
+	// транзакция повторяется, поэтому fn не должна иметь побочных эффектов вне хранилищ.
+	WithTx(ctx context.Context, fn func(tx Stores) error) error
+}
+
+var (
+	_ UnitOfWork = (*TxManager)(nil)
+	_ UnitOfWork = (*MemoryUnitOfWork)(nil)
+)
+
+// TxOptions - настройки транзакций UnitOfWork.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	// MaxRetries - сколько раз повторить транзакцию после конфликта сериализации или взаимоблокировки.
+	MaxRetries int
+}
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+// ParseTxOptions проверяет уровень изоляции и число повторов из конфигурации.
+func ParseTxOptions(isolation string, maxRetries int) (TxOptions, error) {
+	level, ok := isolationLevels[isolation]
+	if !ok {
+		return TxOptions{}, fmt.Errorf("unknown isolation level %q, expected read_committed, repeatable_read or serializable", isolation)
+	}
+	if maxRetries < 0 {
+		return TxOptions{}, fmt.Errorf("transaction retries must not be negative, got %d", maxRetries)
+	}
+	return TxOptions{Isolation: level, MaxRetries: maxRetries}, nil
+}
+
+// TxManager - UnitOfWork поверх транзакций Postgres.
+type TxManager struct {
+	db   *sql.DB
+	opts TxOptions
+}
+
+func NewTxManager(db *sql.DB, opts TxOptions) *TxManager {
+	return &TxManager{db: db, opts: opts}
+}
+
+func (m *TxManager) WithTx(ctx context.Context, fn func(tx Stores) error) error {
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || !retryable(err) || attempt >= m.opts.MaxRetries {
+			return err
+		}
+		// случайная пауза, чтобы столкнувшиеся транзакции не повторились одновременно
+		delay := time.Duration(attempt+1)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *TxManager) run(ctx context.Context, fn func(tx Stores) error) error {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: m.opts.Isolation})
+	if err != nil {
+		return mapError(err)
+	}
+	err = fn(Stores{
+		Persons:     &PersonRepository{db: tx},
+		Imports:     &ImportRepository{db: tx},
+		Idempotency: &IdempotencyRepository{db: tx},
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return mapError(tx.Commit())
+}
+
+// retryable - транзакция отменена из-за конфликта сериализации или взаимоблокировки
+// и может пройти при повторе.
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
+}
+
+// withTx выполняет fn в транзакции. Если репозиторий уже привязан к транзакции UnitOfWork,
+// fn выполняется в ней внутри точки сохранения: ошибка fn откатывает только изменения fn,
+// и вызывающий может продолжить транзакцию (например, найти запись после ErrConflict).
+func withTx(ctx context.Context, db DBTX, fn func(tx *sql.Tx) error) error {
+	var tx *sql.Tx
+	switch db := db.(type) {
+	case *sql.Tx:
+		return withSavepoint(ctx, db, fn)
+	case *sql.DB:
+		var err error
+		if tx, err = db.BeginTx(ctx, nil); err != nil {
+			return mapError(err)
+		}
+	default:
+		return fmt.Errorf("repository: cannot start a transaction on %T", db)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return mapError(tx.Commit())
+}
+
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT repository_call`); err != nil {
+		return mapError(err)
+	}
+	if err := fn(tx); err != nil {
+		tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT repository_call`)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT repository_call`)
+	return mapError(err)
+}
@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeDriver - драйвер без сервера: транзакции только считаются, Commit возвращает
+// по очереди ошибки из commitErrs.
+type fakeDriver struct {
+	begins     int
+	commits    int
+	rollbacks  int
+	commitErrs []error
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver: queries are not supported")
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.begins++
+	return &fakeTx{d: c.d}, nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t *fakeTx) Commit() error {
+	t.d.commits++
+	if len(t.d.commitErrs) == 0 {
+		return nil
+	}
+	err := t.d.commitErrs[0]
+	t.d.commitErrs = t.d.commitErrs[1:]
+	return err
+}
+func (t *fakeTx) Rollback() error { t.d.rollbacks++; return nil }
+
+type fakeConnector struct{ d *fakeDriver }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.d }
+
+// pqErrorCodes - коды SQLSTATE ошибок, которые встречаются в тестах.
+var pqErrorCodes = map[string]pq.ErrorCode{
+	"serialization_failure": "40001",
+	"deadlock_detected":     "40P01",
+	"unique_violation":      "23505",
+}
+
+func pqError(name string) error {
+	return &pq.Error{Code: pqErrorCodes[name]}
+}
+
+func TestTxManagerRetries(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name       string
+		maxRetries int
+		fnErrs     []error
+		commitErrs []error
+		wantCalls  int
+		wantErr    func(error) bool
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+		},
+		{
+			name:       "serialization failure is retried",
+			maxRetries: 2,
+			fnErrs:     []error{pqError("serialization_failure")},
+			wantCalls:  2,
+		},
+		{
+			name:       "deadlock on commit is retried",
+			maxRetries: 2,
+			commitErrs: []error{pqError("deadlock_detected"), pqError("deadlock_detected")},
+			wantCalls:  3,
+		},
+		{
+			name:       "retries are exhausted",
+			maxRetries: 2,
+			fnErrs:     []error{pqError("serialization_failure"), pqError("serialization_failure"), pqError("serialization_failure")},
+			wantCalls:  3,
+			wantErr:    retryable,
+		},
+		{
+			name:       "no retries configured",
+			maxRetries: 0,
+			fnErrs:     []error{pqError("serialization_failure")},
+			wantCalls:  1,
+			wantErr:    retryable,
+		},
+		{
+			name:       "unique violation is not retried",
+			maxRetries: 2,
+			commitErrs: []error{pqError("unique_violation")},
+			wantCalls:  1,
+			wantErr:    func(err error) bool { return errors.Is(err, ErrConflict) },
+		},
+		{
+			name:       "fn error is not retried",
+			maxRetries: 2,
+			fnErrs:     []error{errBoom},
+			wantCalls:  1,
+			wantErr:    func(err error) bool { return errors.Is(err, errBoom) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDriver{commitErrs: tt.commitErrs}
+			db := sql.OpenDB(fakeConnector{d})
+			defer db.Close()
+			m := NewTxManager(db, TxOptions{Isolation: sql.LevelSerializable, MaxRetries: tt.maxRetries})
+
+			calls := 0
+			fnErrs := tt.fnErrs
+			err := m.WithTx(context.Background(), func(tx Stores) error {
+				calls++
+				if tx.Persons == nil || tx.Imports == nil || tx.Idempotency == nil {
+					t.Fatal("stores are not bound to the transaction")
+				}
+				if len(fnErrs) == 0 {
+					return nil
+				}
+				err := fnErrs[0]
+				fnErrs = fnErrs[1:]
+				return err
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("fn called %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("WithTx() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && (err == nil || !tt.wantErr(err)) {
+				t.Errorf("WithTx() error = %v, want a matching error", err)
+			}
+			if d.begins != calls {
+				t.Errorf("%d transactions started for %d calls", d.begins, calls)
+			}
+			if d.commits+d.rollbacks != d.begins {
+				t.Errorf("%d transactions started, but %d committed and %d rolled back", d.begins, d.commits, d.rollbacks)
+			}
+		})
+	}
+}
+
+func TestTxManagerStopsRetryingWhenContextIsDone(t *testing.T) {
+	d := &fakeDriver{}
+	db := sql.OpenDB(fakeConnector{d})
+	defer db.Close()
+	m := NewTxManager(db, TxOptions{MaxRetries: 5})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := m.WithTx(ctx, func(tx Stores) error {
+		calls++
+		cancel()
+		return pqError("serialization_failure")
+	})
+	if calls != 1 || !retryable(err) {
+		t.Errorf("WithTx() = %v after %d calls, want the serialization failure after 1 call", err, calls)
+	}
+}
+
+func TestWithTxRejectsUnknownDBTX(t *testing.T) {
+	err := withTx(context.Background(), DBTX(nil), func(tx *sql.Tx) error {
+		t.Fatal("fn must not be called")
+		return nil
+	})
+	if err == nil {
+		t.Fatal("withTx() error = nil, want an error")
+	}
+}
+
+func TestMemoryUnitOfWorkRollback(t *testing.T) {
+	ctx := context.Background()
+	persons := NewMemoryPersonRepository(false)
+	imports := NewMemoryImportRepository()
+	idempotency := NewMemoryIdempotencyRepository()
+	uow := NewMemoryUnitOfWork(persons, imports, idempotency)
+
+	kept := models.Person{ID: uuid.New(), Name: "Иван", Surname: "Иванов", Version: 1}
+	if err := persons.Create(ctx, kept); err != nil {
+		t.Fatal(err)
+	}
+	imp := models.Import{ID: uuid.New(), Status: models.ImportRunning}
+	if err := imports.CreateImport(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idempotency.AcquireKey(ctx, "scope", "key", "fp", time.Hour, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	errBoom := errors.New("boom")
+	added := models.Person{ID: uuid.New(), Name: "Петр", Surname: "Петров", Version: 1}
+	err := uow.WithTx(ctx, func(tx Stores) error {
+		if err := tx.Persons.Create(ctx, added); err != nil {
+			return err
+		}
+		name := "Иоанн"
+		if _, err := tx.Persons.Update(ctx, kept.ID, 0, models.UpdatePersonRequest{Name: &name}); err != nil {
+			return err
+		}
+		if _, err := tx.Persons.Merge(ctx, kept.ID, []uuid.UUID{added.ID}, func(survivor models.Person, merged []models.Person) (models.Person, error) {
+			return survivor, nil
+		}); err != nil {
+			return err
+		}
+		progress := imp
+		progress.ProcessedRows = 10
+		if err := tx.Imports.SaveProgress(ctx, &progress, []models.ImportError{{Row: 2, Error: "bad"}}); err != nil {
+			return err
+		}
+		if err := tx.Idempotency.SaveResponse(ctx, "scope", "key", models.IdempotentResponse{StatusCode: 201}); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithTx() error = %v, want errBoom", err)
+	}
+
+	got, err := persons.GetByID(ctx, kept.ID, false)
+	if err != nil || got.Name != "Иван" || got.Version != 1 {
+		t.Errorf("kept person = %+v, %v; want the state before the transaction", got, err)
+	}
+	if _, err := persons.GetByID(ctx, added.ID, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("created person is still stored: %v", err)
+	}
+	if _, err := persons.MergedInto(ctx, added.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("merge link is still stored: %v", err)
+	}
+	if history, _ := persons.History(ctx, kept.ID, 10, 0); len(history) != 1 {
+		t.Errorf("history has %d entries, want only the create", len(history))
+	}
+	if got, _ := imports.GetImport(ctx, imp.ID); got.ProcessedRows != 0 {
+		t.Errorf("import progress = %d, want 0", got.ProcessedRows)
+	}
+	if errs, _ := imports.ImportErrors(ctx, imp.ID); len(errs) != 0 {
+		t.Errorf("import errors = %v, want none", errs)
+	}
+	if _, err := idempotency.AcquireKey(ctx, "scope", "key", "fp", time.Hour, time.Minute); !errors.Is(err, ErrKeyInProgress) {
+		t.Errorf("AcquireKey() error = %v, want the key still in progress", err)
+	}
+
+	// после отката транзакции работают как обычно, id истории не повторяются
+	if err := uow.WithTx(ctx, func(tx Stores) error { return tx.Persons.Create(ctx, added) }); err != nil {
+		t.Fatal(err)
+	}
+	history, _ := persons.History(ctx, added.ID, 10, 0)
+	if len(history) != 1 || history[0].ID <= 1 {
+		t.Errorf("history after commit = %+v", history)
+	}
+}
@@ -0,0 +1,207 @@
+// Package search содержит общие для хранилищ части нечеткого поиска по ФИО:
+// разбиение запроса на слова, триграммное сходство как в pg_trgm и подсветку совпадений.
+package search
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Threshold - порог сходства, как pg_trgm.similarity_threshold по умолчанию.
+const Threshold = 0.3
+
+// Tokens разбивает запрос на слова в нижнем регистре без повторов.
+func Tokens(q string) []string {
+	var tokens []string
+	seen := map[string]bool{}
+	for _, w := range words(q) {
+		if !seen[w] {
+			seen[w] = true
+			tokens = append(tokens, w)
+		}
+	}
+	return tokens
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// trigrams повторяет show_trgm: каждое слово дополняется двумя пробелами слева и одним справа.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range words(s) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// Similarity - доля общих триграмм, как similarity() в pg_trgm.
+func Similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// Highlight оборачивает в <em> части text, совпавшие с одним из слов запроса:
+// подстроку при точном вхождении, иначе целое слово, достаточно похожее на слово запроса.
+// Возвращает "", если совпадений нет. Текст экранируется для HTML.
+func Highlight(text string, tokens []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	marked := make([]bool, len(runes))
+	found := false
+
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(lower[start:end])
+		for _, t := range tokens {
+			if i := strings.Index(word, t); i >= 0 {
+				from := start + len([]rune(word[:i]))
+				for j := from; j < from+len([]rune(t)); j++ {
+					marked[j] = true
+				}
+				found = true
+			} else if Similarity(word, t) >= Threshold {
+				for j := start; j < end; j++ {
+					marked[j] = true
+				}
+				found = true
+			}
+		}
+		start = end
+	}
+	if !found {
+		return ""
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		part := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			part = "<em>" + part + "</em>"
+		}
+		b.WriteString(part)
+		i = j
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Веса ts_rank по умолчанию: у лексем без метки вес D.
+const rankWeight = 0.1
+
+// ContainsAll сообщает, есть ли в doc все слова tokens, как
+// to_tsvector('simple', doc) @@ plainto_tsquery('simple', запрос).
+func ContainsAll(doc string, tokens []string) bool {
+	positions := wordPositions(doc)
+	for _, t := range tokens {
+		if len(positions[t]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Rank повторяет ts_rank(to_tsvector('simple', doc), plainto_tsquery('simple', запрос))
+// с весами и нормализацией по умолчанию: для одного слова учитывается число вхождений,
+// для нескольких - близость найденных слов друг к другу.
+func Rank(doc string, tokens []string) float64 {
+	positions := wordPositions(doc)
+	if len(tokens) < 2 {
+		return rankOr(positions, tokens)
+	}
+
+	// calc_rank_and: 1 - произведение (1 - вклад) по всем парам позиций разных слов запроса
+	res := -1.0
+	for i := range tokens {
+		for k := 0; k < i; k++ {
+			for _, pi := range positions[tokens[i]] {
+				for _, pk := range positions[tokens[k]] {
+					dist := pi - pk
+					if dist < 0 {
+						dist = -dist
+					}
+					if dist == 0 {
+						continue
+					}
+					w := math.Sqrt(rankWeight * rankWeight * wordDistance(dist))
+					if res < 0 {
+						res = w
+					} else {
+						res = 1 - (1-res)*(1-w)
+					}
+				}
+			}
+		}
+	}
+	if res < 0 {
+		return 1e-20
+	}
+	return res
+}
+
+// rankOr повторяет calc_rank_or: сумма 1/i^2 по вхождениям каждого слова, нормированная на pi^2/6.
+func rankOr(positions map[string][]int, tokens []string) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	var res float64
+	for _, t := range tokens {
+		n := len(positions[t])
+		if n == 0 {
+			continue
+		}
+		var sum float64
+		for j := 1; j <= n; j++ {
+			sum += rankWeight / float64(j*j)
+		}
+		res += sum / 1.64493406685
+	}
+	return res / float64(len(tokens))
+}
+
+func wordDistance(dist int) float64 {
+	if dist > 100 {
+		return 1e-30
+	}
+	return 1 / (1.005 + 0.05*math.Exp(float64(dist)/1.5-2))
+}
+
+// wordPositions - позиции слов doc, начиная с 1, как в tsvector.
+func wordPositions(doc string) map[string][]int {
+	positions := map[string][]int{}
+	for i, w := range words(doc) {
+		positions[w] = append(positions[w], i+1)
+	}
+	return positions
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	got := Tokens("  Иван-Петров, ivan IVAN o'brien ")
+	want := []string{"иван", "петров", "ivan", "o", "brien"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens = %v, want %v", got, want)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	// значения совпадают с similarity() из pg_trgm
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"word", "word", 1},
+		{"word", "two words", 0.36363637},
+		{"ivanov", "ivanof", 0.5555556},
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	// значения совпадают с ts_rank(to_tsvector('simple', doc), plainto_tsquery('simple', query))
+	tests := []struct {
+		doc    string
+		tokens []string
+		want   float64
+	}{
+		{"Ivan Petrov", []string{"ivan"}, 0.0607927},
+		{"Ivan Petrov", []string{"ivan", "petrov"}, 0.0991032},
+		{"Ivan Petrov", []string{"ivan", "sidorov"}, 1e-20},
+		{"Ivan Petrov", []string{"oleg"}, 0},
+		{"Ivan Ivan", []string{"ivan"}, 0.0759909},
+	}
+	for _, tt := range tests {
+		if got := Rank(tt.doc, tt.tokens); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("Rank(%q, %v) = %v, want %v", tt.doc, tt.tokens, got, tt.want)
+		}
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	if !ContainsAll("Ivan Petrov Sergeevich", []string{"petrov", "ivan"}) {
+		t.Error("all words are present")
+	}
+	if ContainsAll("Ivan Petrov", []string{"ivan", "sidorov"}) {
+		t.Error("sidorov is missing")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens []string
+		want   string
+	}{
+		{"Ivanov", []string{"iva"}, "<em>Iva</em>nov"},
+		{"Ivanov", []string{"ivanof"}, "<em>Ivanov</em>"},
+		{"Anna-Maria", []string{"maria"}, "Anna-<em>Maria</em>"},
+		{"Petrov", []string{"ivan"}, ""},
+		{"<b>", []string{"b"}, "&lt;<em>b</em>&gt;"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.tokens); got != tt.want {
+			t.Errorf("Highlight(%q, %v) = %q, want %q", tt.text, tt.tokens, got, tt.want)
+		}
+	}
+}
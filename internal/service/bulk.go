@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"effective-mobile-task/internal/client"
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+)
+
+// bulkEnrichWorkers ограничивает число одновременных обогащений при массовом создании;
+// повторные имена внутри пачки все равно попадают в кэш и объединяются HTTP-клиентом.
+const bulkEnrichWorkers = 8
+
+// errBulkRejected откатывает транзакцию пачки в режиме atomic, когда хранилище отклонило
+// отдельные элементы; статусы элементов уже записаны в результаты.
+var errBulkRejected = errors.New("bulk rejected")
+
+// CreateBulk создает людей пачкой и возвращает статус для каждого элемента в порядке запроса.
+// Повтор ФИО внутри пачки и дубликат уже сохраненного человека получают статус duplicate.
+// В режиме atomic любой неуспешный элемент отменяет сохранение всей пачки: корректные
+// элементы получают статус skipped.
+// Ошибка возвращается только при сбое хранилища.
+func (s *PersonService) CreateBulk(ctx context.Context, reqs []models.CreatePersonRequest, atomic bool) ([]models.BulkItemResult, error) {
+	batch := s.prepareBulk(reqs, nil)
+	if atomic && len(batch.created) < len(reqs) {
+		for _, i := range batch.created {
+			batch.results[i].Status = models.BulkSkipped
+		}
+		return batch.results, nil
+	}
+
+	err := s.inTx(ctx, func(tx repository.Stores) error {
+		return s.saveBulk(ctx, tx.Persons, batch, atomic)
+	})
+	if err != nil && !errors.Is(err, errBulkRejected) {
+		return nil, err
+	}
+	return batch.results, nil
+}
+
+// bulkBatch - проверенная и обогащенная пачка: people[k] из запроса reqs[k] сохраняется
+// как элемент created[k].
+type bulkBatch struct {
+	results []models.BulkItemResult
+	reqs    []models.CreatePersonRequest
+	people  []models.Person
+	created []int
+}
+
+// prepareBulk проверяет и обогащает пачку без обращения к хранилищу, чтобы запросы
+// к внешним API не выполнялись внутри транзакции. ids[i] - заранее выбранный id для reqs[i],
+// nil - случайные id.
+func (s *PersonService) prepareBulk(reqs []models.CreatePersonRequest, ids []uuid.UUID) *bulkBatch {
+	results := make([]models.BulkItemResult, len(reqs))
+	originals := make([]models.CreatePersonRequest, len(reqs))
+	pending := make([]int, 0, len(reqs))
+	seen := make(map[string]int, len(reqs))
+	for i := range reqs {
+		results[i].Index = i
+		originals[i] = canonicalizeRequest(&reqs[i])
+		if err := Validate(&reqs[i]); err != nil {
+			results[i].Status, results[i].Error = models.BulkValidationError, err.Error()
+			continue
+		}
+		req := reqs[i]
+		key := strings.ToLower(req.Name + "\x00" + req.Surname + "\x00" + req.Patronymic)
+		if first, ok := seen[key]; ok {
+			results[i].Status, results[i].DuplicateOf = models.BulkDuplicate, &first
+			results[i].Error = fmt.Sprintf("same person as item %d", first)
+			continue
+		}
+		seen[key] = i
+		pending = append(pending, i)
+	}
+
+	now := time.Now()
+	people := make([]*models.Person, len(reqs))
+	var g errgroup.Group
+	g.SetLimit(bulkEnrichWorkers)
+	for _, i := range pending {
+		g.Go(func() error {
+			req := reqs[i]
+			enrichment, err := s.enricher.Enrich(client.Query{Name: req.Name, Surname: req.Surname, Patronymic: req.Patronymic})
+			if err != nil {
+				results[i].Status, results[i].Error = models.BulkEnrichmentError, err.Error()
+				return nil
+			}
+			people[i] = newPerson(req, originals[i], enrichment, now)
+			if ids != nil {
+				people[i].ID = ids[i]
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	batch := &bulkBatch{results: results}
+	for _, i := range pending {
+		if people[i] != nil {
+			batch.reqs = append(batch.reqs, reqs[i])
+			batch.people = append(batch.people, *people[i])
+			batch.created = append(batch.created, i)
+		}
+	}
+	return batch
+}
+
+// saveBulk сохраняет пачку в repo и проставляет статусы сохраненных элементов. Элемент, у которого
+// в repo уже есть дубликат по политике Duplicates, получает статус duplicate с existing_id, как в Create.
+// При повторе транзакции вызывается заново, поэтому статусы каждый раз выставляются с нуля.
+// В режиме atomic, если отклонен хотя бы один элемент, остальные получают статус skipped,
+// а saveBulk возвращает errBulkRejected, чтобы откатить транзакцию.
+func (s *PersonService) saveBulk(ctx context.Context, repo repository.PersonStore, b *bulkBatch, atomic bool) error {
+	rejected := false
+	var people []models.Person
+	var saved []int
+	for k, i := range b.created {
+		res := &b.results[i]
+		*res = models.BulkItemResult{Index: i}
+		existing, err := s.findDuplicate(ctx, repo, b.reqs[k])
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			res.Status, res.ExistingID = models.BulkDuplicate, &existing.ID
+			res.Error = (&DuplicateError{Existing: existing}).Error()
+			rejected = true
+			continue
+		}
+		res.Status, res.Person = models.BulkCreated, &b.people[k]
+		people = append(people, b.people[k])
+		saved = append(saved, k)
+	}
+
+	if !atomic || !rejected {
+		err := repo.CreateMany(ctx, people)
+		if err != nil && !errors.Is(err, repository.ErrConflict) && !errors.Is(err, repository.ErrConstraint) {
+			return err
+		}
+		if err != nil {
+			// пачка отклонена из-за отдельных строк: сохраняем по одному, чтобы найти виноватых
+			for _, k := range saved {
+				if err := repo.Create(ctx, b.people[k]); err != nil {
+					res := &b.results[b.created[k]]
+					if err := bulkItemError(res, err); err != nil {
+						return err
+					}
+					if res.Status == models.BulkDuplicate {
+						if existing, findErr := repo.FindDuplicate(ctx, b.reqs[k], 0); findErr == nil {
+							res.ExistingID = &existing.ID
+						}
+					}
+					rejected = true
+				}
+			}
+		}
+	}
+
+	if atomic && rejected {
+		for _, i := range b.created {
+			if b.results[i].Status == models.BulkCreated {
+				b.results[i].Status, b.results[i].Person = models.BulkSkipped, nil
+			}
+		}
+		return errBulkRejected
+	}
+	return nil
+}
+
+// bulkItemError записывает ошибку сохранения в результат элемента; ошибки, не относящиеся
+// к данным элемента, возвращаются как есть.
+func bulkItemError(res *models.BulkItemResult, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrConflict):
+		res.Status = models.BulkDuplicate
+	case errors.Is(err, repository.ErrConstraint):
+		res.Status = models.BulkValidationError
+	default:
+		return err
+	}
+	res.Person, res.Error = nil, err.Error()
+	return nil
+}
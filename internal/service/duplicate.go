@@ -0,0 +1,374 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/repository"
+	"github.com/google/uuid"
+)
+
+type DuplicateMode string
+
+const (
+	// DuplicatesOff - Create не ищет дубликаты сам, но одинаковое ФИО все равно отклоняет хранилище.
+	DuplicatesOff DuplicateMode = "off"
+	// DuplicatesExact - дубликат - человек с тем же ФИО без учета регистра и лишних пробелов.
+	DuplicatesExact DuplicateMode = "exact"
+	// DuplicatesFuzzy - дубликат - человек с триграммным сходством ФИО не ниже порога.
+	DuplicatesFuzzy DuplicateMode = "fuzzy"
+)
+
+// DuplicatePolicy - как Create ищет уже существующего человека с тем же ФИО.
+type DuplicatePolicy struct {
+	Mode DuplicateMode
+	// Threshold - порог сходства для DuplicatesFuzzy, от 0 до 1.
+	Threshold float64
+}
+
+// ParseDuplicatePolicy проверяет режим и порог из конфигурации.
+func ParseDuplicatePolicy(mode string, threshold float64) (DuplicatePolicy, error) {
+	p := DuplicatePolicy{Mode: DuplicateMode(mode), Threshold: threshold}
+	switch p.Mode {
+	case DuplicatesOff, DuplicatesExact:
+	case DuplicatesFuzzy:
+		if threshold <= 0 || threshold > 1 {
+			return p, fmt.Errorf("duplicate threshold must be in (0, 1], got %v", threshold)
+		}
+	default:
+		return p, fmt.Errorf("unknown duplicate detection mode %q, expected off, exact or fuzzy", mode)
+	}
+	return p, nil
+}
+
+// DuplicateError - Create нашел уже существующего человека с тем же или похожим ФИО.
+// Сравнивается с repository.ErrConflict через errors.Is.
+type DuplicateError struct {
+	Existing *models.Person
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("person already exists: %s", e.Existing.ID)
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return repository.ErrConflict
+}
+
+// checkDuplicate возвращает *DuplicateError, если в repo есть дубликат req по политике сервиса.
+func (s *PersonService) checkDuplicate(ctx context.Context, repo repository.PersonStore, req models.CreatePersonRequest) error {
+	existing, err := s.findDuplicate(ctx, repo, req)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return &DuplicateError{Existing: existing}
+	}
+	return nil
+}
+
+// findDuplicate ищет дубликат по политике сервиса; nil - дубликата нет.
+func (s *PersonService) findDuplicate(ctx context.Context, repo repository.PersonStore, req models.CreatePersonRequest) (*models.Person, error) {
+	threshold := 0.0
+	switch s.Duplicates.Mode {
+	case DuplicatesOff, "":
+		return nil, nil
+	case DuplicatesFuzzy:
+		threshold = s.Duplicates.Threshold
+	}
+
+	existing, err := repo.FindDuplicate(ctx, req, threshold)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	existing.Age = existing.CurrentAge(time.Now())
+	return existing, nil
+}
+
+// duplicatePairsPerCluster - сколько похожих пар запрашивается у хранилища на одну группу ответа.
+const duplicatePairsPerCluster = 10
+
+// FindDuplicates группирует похожих людей: пары со сходством ФИО не ниже threshold
+// объединяются в группы по транзитивности. Группы упорядочены по убыванию оценки.
+func (s *PersonService) FindDuplicates(ctx context.Context, threshold float64, limit int) ([]models.DuplicateCluster, error) {
+	pairs, err := s.repo.SimilarPairs(ctx, threshold, limit*duplicatePairsPerCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := map[uuid.UUID]uuid.UUID{}
+	var find func(id uuid.UUID) uuid.UUID
+	find = func(id uuid.UUID) uuid.UUID {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = find(p)
+			return parent[id]
+		}
+		parent[id] = id
+		return id
+	}
+	var ids []uuid.UUID
+	for _, p := range pairs {
+		for _, id := range []uuid.UUID{p.A, p.B} {
+			if _, ok := parent[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		parent[find(p.A)] = find(p.B)
+	}
+
+	people, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	byID := make(map[uuid.UUID]*models.Person, len(people))
+	for i := range people {
+		people[i].Age = people[i].CurrentAge(now)
+		byID[people[i].ID] = &people[i]
+	}
+
+	type group struct {
+		cluster models.DuplicateCluster
+		total   float64
+		pairs   int
+	}
+	groups := map[uuid.UUID]*group{}
+	var order []uuid.UUID
+	for _, p := range pairs {
+		a, b := byID[p.A], byID[p.B]
+		if a == nil || b == nil {
+			// удален между запросами
+			continue
+		}
+		root := find(p.A)
+		g, ok := groups[root]
+		if !ok {
+			g = &group{}
+			groups[root] = g
+			order = append(order, root)
+		}
+		g.total += pairScore(p.Similarity, a, b)
+		g.pairs++
+	}
+
+	clusters := make([]models.DuplicateCluster, 0, len(order))
+	for _, root := range order {
+		g := groups[root]
+		for _, id := range ids {
+			if find(id) == root && byID[id] != nil {
+				g.cluster.Persons = append(g.cluster.Persons, *byID[id])
+			}
+		}
+		slices.SortFunc(g.cluster.Persons, func(a, b models.Person) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+		g.cluster.Score = math.Round(g.total/float64(g.pairs)*1000) / 1000
+		g.cluster.MatchingAttributes = matchingAttributes(g.cluster.Persons)
+		clusters = append(clusters, g.cluster)
+	}
+	slices.SortStableFunc(clusters, func(a, b models.DuplicateCluster) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if limit < len(clusters) {
+		clusters = clusters[:limit]
+	}
+	return clusters, nil
+}
+
+// pairScore - 0.7 от сходства ФИО и 0.3 от доли совпавших атрибутов.
+func pairScore(similarity float64, a, b *models.Person) float64 {
+	attrs := matchingAttributes([]models.Person{*a, *b})
+	return 0.7*similarity + 0.3*float64(len(attrs))/3
+}
+
+// matchingAttributes возвращает известные у всех и одинаковые атрибуты: пол, национальность
+// и возраст с точностью до года.
+func matchingAttributes(people []models.Person) []string {
+	attrs := []string{}
+	same := func(value func(p models.Person) string) bool {
+		first := value(people[0])
+		for _, p := range people {
+			if v := value(p); v == "" || v != first {
+				return false
+			}
+		}
+		return true
+	}
+	if same(func(p models.Person) string { return p.Gender }) {
+		attrs = append(attrs, "gender")
+	}
+	if same(func(p models.Person) string { return p.Nationality }) {
+		attrs = append(attrs, "nationality")
+	}
+	minAge, maxAge := people[0].Age, people[0].Age
+	for _, p := range people {
+		minAge, maxAge = min(minAge, p.Age), max(maxAge, p.Age)
+	}
+	if minAge > 0 && maxAge-minAge <= 1 {
+		attrs = append(attrs, "age")
+	}
+	return attrs
+}
+
+// maxMergeIDs ограничивает число поглощаемых за одно слияние.
+const maxMergeIDs = 100
+
+// mergeFields - поля, значение которых выбирается при слиянии: value для сравнения, copy для переноса.
+var mergeFields = map[string]struct {
+	value func(p *models.Person) string
+	copy  func(dst, src *models.Person)
+}{
+	"name": {
+		func(p *models.Person) string { return p.Name },
+		func(dst, src *models.Person) { dst.Name, dst.NameOriginal = src.Name, src.NameOriginal },
+	},
+	"surname": {
+		func(p *models.Person) string { return p.Surname },
+		func(dst, src *models.Person) { dst.Surname, dst.SurnameOriginal = src.Surname, src.SurnameOriginal },
+	},
+	"patronymic": {
+		func(p *models.Person) string { return p.Patronymic },
+		func(dst, src *models.Person) {
+			dst.Patronymic, dst.PatronymicOriginal = src.Patronymic, src.PatronymicOriginal
+		},
+	},
+	"gender":      {func(p *models.Person) string { return p.Gender }, func(dst, src *models.Person) { dst.Gender = src.Gender }},
+	"nationality": {func(p *models.Person) string { return p.Nationality }, func(dst, src *models.Person) { dst.Nationality = src.Nationality }},
+	"age": {
+		func(p *models.Person) string {
+			if p.BirthYear > 0 {
+				return "y" + strconv.Itoa(p.BirthYear)
+			}
+			if p.Age > 0 {
+				return "a" + strconv.Itoa(p.Age)
+			}
+			return ""
+		},
+		func(dst, src *models.Person) { dst.Age, dst.BirthYear = src.Age, src.BirthYear },
+	},
+}
+
+// Merge поглощает req.MergedIDs выжившим req.SurvivorID: выживший получает поля по правилам
+// req.Fields, поглощенные удаляются, а GetByID по их id возвращает *MergedError.
+// version > 0 проверяется у выжившего.
+func (s *PersonService) Merge(ctx context.Context, req models.MergePersonsRequest, version int) (*models.Person, error) {
+	if err := validateMerge(req); err != nil {
+		return nil, err
+	}
+
+	resolve := func(survivor models.Person, merged []models.Person) (models.Person, error) {
+		if version > 0 && survivor.Version != version {
+			return survivor, repository.ErrVersionMismatch
+		}
+		all := append([]models.Person{survivor}, merged...)
+		result := survivor
+		for name, field := range mergeFields {
+			src := mergeSource(all, field.value, req.Fields[name])
+			field.copy(&result, src)
+		}
+		return result, nil
+	}
+	var person *models.Person
+	err := s.inTx(ctx, func(tx repository.Stores) error {
+		var err error
+		person, err = tx.Persons.Merge(ctx, req.SurvivorID, req.MergedIDs, resolve)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	person.Age = person.CurrentAge(time.Now())
+	return person, nil
+}
+
+func validateMerge(req models.MergePersonsRequest) error {
+	if len(req.MergedIDs) == 0 {
+		return fmt.Errorf("%w: merged_ids is required", ErrValidation)
+	}
+	if len(req.MergedIDs) > maxMergeIDs {
+		return fmt.Errorf("%w: at most %d persons can be merged at once", ErrValidation, maxMergeIDs)
+	}
+	ids := map[uuid.UUID]bool{req.SurvivorID: true}
+	for _, id := range req.MergedIDs {
+		if ids[id] {
+			return fmt.Errorf("%w: person %s is listed twice", ErrValidation, id)
+		}
+		ids[id] = true
+	}
+
+	for name, rule := range req.Fields {
+		if _, ok := mergeFields[name]; !ok {
+			return fmt.Errorf("%w: unknown merge field %q", ErrValidation, name)
+		}
+		switch rule {
+		case "survivor", "newest", "oldest", "most_common":
+			continue
+		}
+		if id, err := uuid.Parse(rule); err != nil || !ids[id] {
+			return fmt.Errorf("%w: %s: rule must be survivor, newest, oldest, most_common or id of a merged person", ErrValidation, name)
+		}
+	}
+	return nil
+}
+
+// mergeSource выбирает запись, из которой берется поле. Правила, кроме явного id, смотрят
+// только на записи с непустым значением; если таких нет, берется выживший all[0].
+func mergeSource(all []models.Person, value func(p *models.Person) string, rule string) *models.Person {
+	if id, err := uuid.Parse(rule); err == nil {
+		for i := range all {
+			if all[i].ID == id {
+				return &all[i]
+			}
+		}
+	}
+
+	var best *models.Person
+	counts := map[string]int{}
+	for i := range all {
+		p := &all[i]
+		v := value(p)
+		if v == "" {
+			continue
+		}
+		counts[v]++
+		switch {
+		case best == nil:
+			best = p
+		case rule == "newest" && p.UpdatedAt.After(best.UpdatedAt):
+			best = p
+		case rule == "oldest" && p.CreatedAt.Before(best.CreatedAt):
+			best = p
+		case rule == "most_common" && counts[v] > counts[value(best)]:
+			best = p
+		}
+	}
+	if best == nil {
+		return &all[0]
+	}
+	return best
+}
+
+// MergedError - запрошенный человек поглощен другим при слиянии.
+// Сравнивается с repository.ErrNotFound через errors.Is.
+type MergedError struct {
+	ID   uuid.UUID
+	Into uuid.UUID
+}
+
+func (e *MergedError) Error() string {
+	return fmt.Sprintf("person %s was merged into %s", e.ID, e.Into)
+}
+
+func (e *MergedError) Unwrap() error {
+	return repository.ErrNotFound
+}
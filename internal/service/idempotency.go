@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/repository"
+)
+
+// maxIdempotencyKeyLength - наибольшая длина Idempotency-Key, как у столбца key.
+const maxIdempotencyKeyLength = 255
+
+type IdempotencyService struct {
+	store       repository.IdempotencyStore
+	ttl         time.Duration
+	lockTimeout time.Duration
+}
+
+// NewIdempotencyService создает сервис, хранящий ответы ttl. Через lockTimeout незавершенный
+// запрос считается брошенным (сервер упал посреди обработки) и его ключ может занять повтор,
+// поэтому lockTimeout должен быть больше времени обработки запроса - см. CheckIdempotencyLockTimeout.
+func NewIdempotencyService(store repository.IdempotencyStore, ttl, lockTimeout time.Duration) *IdempotencyService {
+	return &IdempotencyService{store: store, ttl: ttl, lockTimeout: lockTimeout}
+}
+
+// CheckIdempotencyLockTimeout проверяет, что ключ не освободится, пока запрос еще может
+// отправить ответ, то есть lockTimeout больше WriteTimeout сервера.
+func CheckIdempotencyLockTimeout(lockTimeout, writeTimeout time.Duration) error {
+	if lockTimeout <= writeTimeout {
+		return fmt.Errorf("idempotency lock timeout %s must be greater than server write timeout %s", lockTimeout, writeTimeout)
+	}
+	return nil
+}
+
+// Begin занимает ключ под запрос. Возвращает nil, если запрос нужно выполнить и затем вызвать
+// Complete или Abort, или ответ, сохраненный для прошлого такого же запроса.
+// Отпечаток запроса - хэш request; тот же ключ с другим запросом дает repository.ErrKeyMismatch.
+func (s *IdempotencyService) Begin(ctx context.Context, scope, key string, request []byte) (*models.IdempotentResponse, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%w: idempotency key is longer than %d bytes", ErrValidation, maxIdempotencyKeyLength)
+	}
+	sum := sha256.Sum256(request)
+	return s.store.AcquireKey(ctx, scope, key, hex.EncodeToString(sum[:]), s.ttl, s.lockTimeout)
+}
+
+// Complete сохраняет ответ для повторов.
+func (s *IdempotencyService) Complete(ctx context.Context, scope, key string, resp models.IdempotentResponse) error {
+	return s.store.SaveResponse(ctx, scope, key, resp)
+}
+
+// Abort освобождает ключ, если ответ не стоит сохранять: повтор выполнит запрос заново.
+func (s *IdempotencyService) Abort(ctx context.Context, scope, key string) error {
+	return s.store.ReleaseKey(ctx, scope, key)
+}
+
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.store.PurgeExpiredKeys(ctx)
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"effective-mobile-task/internal/actor"
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/repository"
+	"github.com/google/uuid"
+)
+
+// importBatchSize - сколько строк импорта обогащается и сохраняется между сохранениями прогресса.
+const importBatchSize = 100
+
+// importFields - поля человека, которые можно сопоставить столбцам файла.
+var importFields = []string{"name", "surname", "patronymic"}
+
+type ImportService struct {
+	store   repository.ImportStore
+	persons *PersonService
+}
+
+func NewImportService(store repository.ImportStore, persons *PersonService) *ImportService {
+	return &ImportService{store: store, persons: persons}
+}
+
+// ImportRequest - загруженный файл и параметры его разбора.
+type ImportRequest struct {
+	Format string
+	// Mapping - поле человека -> столбец CSV или ключ NDJSON; несопоставленные поля ищутся по своему имени
+	Mapping   map[string]string
+	Delimiter string
+	Data      []byte
+}
+
+// Create проверяет файл целиком и ставит импорт в очередь. Ошибки формата и сопоставления
+// возвращаются сразу как ErrValidation, ошибки отдельных строк - в ходе импорта.
+func (s *ImportService) Create(ctx context.Context, req ImportRequest) (*models.Import, error) {
+	imp := &models.Import{
+		ID:        uuid.New(),
+		Status:    models.ImportPending,
+		Format:    req.Format,
+		Mapping:   map[string]string{},
+		Delimiter: req.Delimiter,
+		Data:      req.Data,
+		CreatedBy: actor.FromContext(ctx),
+	}
+	if imp.Format != models.ImportCSV && imp.Format != models.ImportNDJSON {
+		return nil, fmt.Errorf("%w: unsupported format %q, expected csv or ndjson", ErrValidation, imp.Format)
+	}
+	if imp.Format == models.ImportCSV && imp.Delimiter == "" {
+		imp.Delimiter = ","
+	}
+	for field, column := range req.Mapping {
+		if !slices.Contains(importFields, field) {
+			return nil, fmt.Errorf("%w: unknown mapping field %q", ErrValidation, field)
+		}
+		imp.Mapping[field] = column
+	}
+
+	rows, columns, err := parseImport(imp)
+	if err != nil {
+		return nil, err
+	}
+	imp.Columns, imp.TotalRows = columns, len(rows)
+
+	if err := s.store.CreateImport(ctx, *imp); err != nil {
+		return nil, err
+	}
+	imp.Data = nil
+	return imp, nil
+}
+
+func (s *ImportService) Get(ctx context.Context, id uuid.UUID) (*models.Import, error) {
+	return s.store.GetImport(ctx, id)
+}
+
+// Errors возвращает импорт и строки, которые не удалось импортировать.
+func (s *ImportService) Errors(ctx context.Context, id uuid.UUID) (*models.Import, []models.ImportError, error) {
+	imp, err := s.store.GetImport(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	errs, err := s.store.ImportErrors(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return imp, errs, nil
+}
+
+// ProcessNext берет в работу один импорт и доводит его до конца. Возвращает false, если очередь пуста.
+// Импорт завершается с ошибкой, только если не разбирается сам файл. При отмене ctx и сбоях
+// хранилища или сети импорт остается в статусе running и продолжается с последней сохраненной
+// пачки, когда истечет lease.
+func (s *ImportService) ProcessNext(ctx context.Context, lease time.Duration) (bool, error) {
+	imp, err := s.store.ClaimImport(ctx, lease)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = s.process(actor.WithName(ctx, imp.CreatedBy), imp)
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+	if errors.Is(err, ErrValidation) {
+		if finishErr := s.store.FinishImport(ctx, imp.ID, models.ImportFailed, err.Error()); finishErr != nil {
+			return true, finishErr
+		}
+		return true, fmt.Errorf("import %s failed: %w", imp.ID, err)
+	}
+	if err != nil {
+		return true, fmt.Errorf("import %s interrupted, will resume after lease: %w", imp.ID, err)
+	}
+	return true, s.store.FinishImport(ctx, imp.ID, models.ImportCompleted, "")
+}
+
+func (s *ImportService) process(ctx context.Context, imp *models.Import) error {
+	rows, _, err := parseImport(imp)
+	if err != nil {
+		return err
+	}
+
+	// прогресс сохраняется после каждой пачки, поэтому после сбоя повторяется не больше одной пачки
+	resumed := imp.Attempts > 1
+	for start := imp.ProcessedRows; start < len(rows); start += importBatchSize {
+		if err := s.importBatch(ctx, imp, rows[start:min(start+importBatchSize, len(rows))], resumed); err != nil {
+			return err
+		}
+		resumed = false
+	}
+	return nil
+}
+
+// importBatch сохраняет пачку строк и прогресс импорта в одной транзакции. Id людей выводятся
+// из id импорта и номера строки, так что при повторе пачки после сбоя уже сохраненные строки
+// узнаются и не дублируются.
+func (s *ImportService) importBatch(ctx context.Context, imp *models.Import, rows []importRow, resumed bool) error {
+	var reqs []models.CreatePersonRequest
+	var ids []uuid.UUID
+	var pending, invalid []importRow
+	existing := 0
+
+	for _, row := range rows {
+		if row.err != nil {
+			invalid = append(invalid, row)
+			continue
+		}
+		id := uuid.NewSHA1(imp.ID, []byte(strconv.Itoa(row.line)))
+		if resumed {
+			_, err := s.persons.repo.GetByID(ctx, id, true)
+			if err == nil {
+				existing++
+				continue
+			}
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+		}
+		reqs = append(reqs, row.req)
+		ids = append(ids, id)
+		pending = append(pending, row)
+	}
+
+	batch := s.persons.prepareBulk(reqs, ids)
+	var progress models.Import
+	err := s.persons.inTx(ctx, func(tx repository.Stores) error {
+		if err := s.persons.saveBulk(ctx, tx.Persons, batch, false); err != nil {
+			return err
+		}
+
+		var errs []models.ImportError
+		for _, row := range invalid {
+			errs = append(errs, models.ImportError{Row: row.line, Record: row.record, Error: row.err.Error()})
+		}
+		created := existing
+		for i, res := range batch.results {
+			if res.Status == models.BulkCreated {
+				created++
+				continue
+			}
+			row := pending[i]
+			msg := res.Status + ": " + res.Error
+			if res.DuplicateOf != nil {
+				msg = fmt.Sprintf("%s: same person as line %d", res.Status, pending[*res.DuplicateOf].line)
+			}
+			errs = append(errs, models.ImportError{Row: row.line, Record: row.record, Error: msg})
+		}
+
+		progress = *imp
+		progress.ProcessedRows += len(rows)
+		progress.CreatedRows += created
+		progress.FailedRows += len(errs)
+		return tx.Imports.SaveProgress(ctx, &progress, errs)
+	})
+	if err != nil {
+		return err
+	}
+	imp.ProcessedRows, imp.CreatedRows, imp.FailedRows = progress.ProcessedRows, progress.CreatedRows, progress.FailedRows
+	return nil
+}
+
+type importRow struct {
+	// line - номер строки файла, с которой начинается запись
+	line   int
+	record []string
+	req    models.CreatePersonRequest
+	err    error
+}
+
+// parseImport разбирает файл импорта в строки и возвращает заголовок для выгрузки ошибок.
+func parseImport(imp *models.Import) ([]importRow, []string, error) {
+	data := bytes.TrimPrefix(imp.Data, []byte("\ufeff"))
+	if imp.Format == models.ImportNDJSON {
+		rows, err := parseNDJSON(data, imp.Mapping)
+		return rows, []string{"record"}, err
+	}
+	return parseCSV(data, imp.Delimiter, imp.Mapping)
+}
+
+func parseCSV(data []byte, delimiter string, mapping map[string]string) ([]importRow, []string, error) {
+	comma, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || comma == '"' || comma == '\r' || comma == '\n' {
+		return nil, nil, fmt.Errorf("%w: invalid delimiter %q", ErrValidation, delimiter)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: cannot read CSV header: %v", ErrValidation, err)
+	}
+
+	index := map[string]int{}
+	for _, field := range importFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		i := slices.Index(header, column)
+		switch {
+		case i >= 0:
+			index[field] = i
+		case mapped || field != "patronymic":
+			return nil, nil, fmt.Errorf("%w: column %q for %s not found in CSV header", ErrValidation, column, field)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return rows, header, nil
+			}
+			return nil, nil, fmt.Errorf("%w: invalid CSV: %v", ErrValidation, err)
+		}
+		line, _ := r.FieldPos(0)
+
+		row := importRow{line: line, record: record}
+		values := map[string]string{}
+		for field, i := range index {
+			if i >= len(record) {
+				row.err = fmt.Errorf("%w: column %q is missing", ErrValidation, header[i])
+				break
+			}
+			values[field] = record[i]
+		}
+		row.req = models.CreatePersonRequest{Name: values["name"], Surname: values["surname"], Patronymic: values["patronymic"]}
+		rows = append(rows, row)
+	}
+}
+
+func parseNDJSON(data []byte, mapping map[string]string) ([]importRow, error) {
+	var rows []importRow
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		row := importRow{line: line, record: []string{string(text)}}
+
+		var obj map[string]interface{}
+		if err := json.Unmarshal(text, &obj); err != nil {
+			row.err = fmt.Errorf("%w: invalid JSON: %v", ErrValidation, err)
+			rows = append(rows, row)
+			continue
+		}
+		values := map[string]string{}
+		for _, field := range importFields {
+			key, mapped := mapping[field]
+			if !mapped {
+				key = field
+			}
+			switch v := obj[key].(type) {
+			case string:
+				values[field] = v
+			case nil:
+			default:
+				row.err = fmt.Errorf("%w: %q must be a string", ErrValidation, key)
+			}
+		}
+		row.req = models.CreatePersonRequest{Name: values["name"], Surname: values["surname"], Patronymic: values["patronymic"]}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: invalid NDJSON: %v", ErrValidation, err)
+	}
+	return rows, nil
+}
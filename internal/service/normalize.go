@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+// nameParticles - частицы фамилий, которые в каноническом виде пишутся строчными,
+// если стоят не первым словом фамилии: "de la Cruz", "van der Berg", "bin Laden".
+var nameParticles = map[string]bool{
+	"de": true, "da": true, "di": true, "do": true, "du": true, "dos": true, "das": true,
+	"del": true, "della": true, "der": true, "den": true, "la": true, "le": true,
+	"van": true, "von": true, "ter": true, "y": true, "e": true, "bin": true, "ibn": true,
+}
+
+// CanonicalName приводит имя или отчество к каноническому виду: убирает пробелы по краям и
+// повторные пробелы, нормализует Unicode в NFC и пишет слова с заглавной буквы, каждую часть
+// через дефис отдельно ("анна-мария" -> "Анна-Мария", "la toya" -> "La Toya").
+// Слова в смешанном регистре (McDonald) сохраняются как есть.
+func CanonicalName(s string) string {
+	return canonicalName(s, false)
+}
+
+// CanonicalSurname - CanonicalName для фамилии: частицы из nameParticles и префиксы d' и l'
+// не в первом слове пишутся строчными ("DE LA CRUZ" -> "De la Cruz", "giscard d'estaing" ->
+// "Giscard d'Estaing"), в первом слове - с заглавной ("van damme" -> "Van Damme").
+func CanonicalSurname(s string) string {
+	return canonicalName(s, true)
+}
+
+func canonicalName(s string, surname bool) string {
+	words := strings.Fields(norm.NFC.String(s))
+	for i, w := range words {
+		lowerParticles := surname && i > 0
+		if lowerParticles && nameParticles[strings.ToLower(w)] {
+			words[i] = strings.ToLower(w)
+			continue
+		}
+		parts := strings.Split(w, "-")
+		for j, p := range parts {
+			parts[j] = titleWord(p, lowerParticles)
+		}
+		words[i] = strings.Join(parts, "-")
+	}
+	return strings.Join(words, " ")
+}
+
+// titleWord пишет слово с заглавной буквы, если оно не в смешанном регистре. Слово с
+// однобуквенным префиксом и апострофом пишется по частям: O'Brien, D'Angelo, а с lowerParticles
+// префиксы d' и l' остаются строчными: d'Artagnan.
+func titleWord(w string, lowerParticles bool) string {
+	r := []rune(w)
+	if len(r) > 2 && (r[1] == '\'' || r[1] == '’') {
+		prefix := unicode.ToLower(r[0])
+		if !lowerParticles || prefix != 'd' && prefix != 'l' {
+			prefix = unicode.ToTitle(prefix)
+		}
+		return string(prefix) + string(r[1]) + titleWord(string(r[2:]), false)
+	}
+	if len(r) == 0 || mixedCase(r) {
+		return w
+	}
+	r = []rune(strings.ToLower(w))
+	r[0] = unicode.ToTitle(r[0])
+	return string(r)
+}
+
+// mixedCase - в слове есть строчные буквы и заглавные не только в начале (McDonald, DiCaprio).
+func mixedCase(r []rune) bool {
+	var lower, innerUpper bool
+	for i, c := range r {
+		lower = lower || unicode.IsLower(c)
+		innerUpper = innerUpper || i > 0 && unicode.IsUpper(c)
+	}
+	return lower && innerUpper
+}
+
+// canonicalizeRequest приводит ФИО req к каноническому виду и возвращает исходные значения.
+func canonicalizeRequest(req *models.CreatePersonRequest) models.CreatePersonRequest {
+	original := *req
+	req.Name = CanonicalName(req.Name)
+	req.Surname = CanonicalSurname(req.Surname)
+	req.Patronymic = CanonicalName(req.Patronymic)
+	return original
+}
+
+// canonicalFilter приводит текстовые значения фильтра к виду, в котором хранятся ФИО:
+// NFC и одиночные пробелы. Регистр не меняется - текст сравнивается без его учета.
+func canonicalFilter(f filter.Filter) filter.Filter {
+	conditions := make([]filter.Condition, len(f.Conditions))
+	for i, c := range f.Conditions {
+		if filter.KindOf(c.Field) == filter.KindText && c.Op != filter.OpNull {
+			values := make([]interface{}, len(c.Values))
+			for j, v := range c.Values {
+				values[j] = strings.Join(strings.Fields(norm.NFC.String(v.(string))), " ")
+			}
+			c.Values = values
+		}
+		conditions[i] = c
+	}
+	f.Conditions = conditions
+	return f
+}
@@ -0,0 +1,53 @@
+package service
+
+import "testing"
+
+func TestCanonicalName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"dmitriy", "Dmitriy"},
+		{"  DMITRIY ", "Dmitriy"},
+		{"анна   мария", "Анна Мария"},
+		{"анна-мария", "Анна-Мария"},
+		{"la toya", "La Toya"},
+		{"de la", "De La"},
+		{"d'artagnan", "D'Artagnan"},
+		{"McDonald", "McDonald"},
+		{"ame\u0301lie", "Am\u00e9lie"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CanonicalName(tt.in); got != tt.want {
+			t.Errorf("CanonicalName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalSurname(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"иванов", "Иванов"},
+		{"иванов-петров", "Иванов-Петров"},
+		{"DE LA CRUZ", "De la Cruz"},
+		{"de niro", "De Niro"},
+		{"Van Damme", "Van Damme"},
+		{"van der berg", "Van der Berg"},
+		{"ibn sina", "Ibn Sina"},
+		{"al bin laden", "Al bin Laden"},
+		{"o'brien", "O'Brien"},
+		{"D'ANGELO", "D'Angelo"},
+		{"d'amico", "D'Amico"},
+		{"l'olive", "L'Olive"},
+		{"giscard d'estaing", "Giscard d'Estaing"},
+		{"GISCARD D'ESTAING", "Giscard d'Estaing"},
+		{"o’brien", "O’Brien"},
+		{"DiCaprio", "DiCaprio"},
+	}
+	for _, tt := range tests {
+		if got := CanonicalSurname(tt.in); got != tt.want {
+			t.Errorf("CanonicalSurname(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -1,70 +1,338 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"effective-mobile-task/internal/client"
+	"effective-mobile-task/internal/filter"
 	"effective-mobile-task/internal/models"
 	"effective-mobile-task/internal/repository"
+	"effective-mobile-task/internal/search"
 	"github.com/google/uuid"
 )
 
+var ErrValidation = errors.New("validation failed")
+
+// PersonService читает через repo, а все изменения выполняет в транзакциях transactions.
 type PersonService struct {
-	repo     *repository.PersonRepository
-	enricher *client.EnrichmentClient
+	repo         repository.PersonStore
+	transactions repository.UnitOfWork
+	enricher     client.Enricher
+
+	// Duplicates - поиск дубликатов в Create; нулевое значение отключает поиск.
+	Duplicates DuplicatePolicy
 }
 
-func NewPersonService(r *repository.PersonRepository, e *client.EnrichmentClient) *PersonService {
-	return &PersonService{repo: r, enricher: e}
+func NewPersonService(r repository.PersonStore, transactions repository.UnitOfWork, e client.Enricher) *PersonService {
+	return &PersonService{repo: r, transactions: transactions, enricher: e}
 }
 
+// Create приводит ФИО к каноническому виду, обогащает и сохраняет человека. Если по политике
+// Duplicates найден человек с тем же ФИО, возвращается *DuplicateError с ним, а новая запись не создается.
 func (s *PersonService) Create(ctx context.Context, req models.CreatePersonRequest) (*models.Person, error) {
-	age, err := s.enricher.GetAge(req.Name)
-	if err != nil {
+	original := canonicalizeRequest(&req)
+	if err := Validate(&req); err != nil {
 		return nil, err
 	}
-	gender, err := s.enricher.GetGender(req.Name)
+	// проверка до обогащения, чтобы не обращаться к внешним API ради дубликата
+	if err := s.checkDuplicate(ctx, s.repo, req); err != nil {
+		return nil, err
+	}
+
+	enrichment, err := s.enricher.Enrich(client.Query{
+		Name:       req.Name,
+		Surname:    req.Surname,
+		Patronymic: req.Patronymic,
+	})
 	if err != nil {
 		return nil, err
 	}
-	nationality, err := s.enricher.GetNationality(req.Name)
+
+	person := newPerson(req, original, enrichment, time.Now())
+	err = s.inTx(ctx, func(tx repository.Stores) error {
+		// повторная проверка: похожего человека могли создать, пока шло обогащение
+		if err := s.checkDuplicate(ctx, tx.Persons, req); err != nil {
+			return err
+		}
+		err := tx.Persons.Create(ctx, *person)
+		if errors.Is(err, repository.ErrConflict) {
+			// то же ФИО успели создать параллельно или поиск отключен: отдаем запись, с которой конфликт
+			if existing, findErr := tx.Persons.FindDuplicate(ctx, req, 0); findErr == nil {
+				existing.Age = existing.CurrentAge(time.Now())
+				return &DuplicateError{Existing: existing}
+			}
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return person, nil
+}
+
+// inTx выполняет fn в транзакции. При повторе транзакции fn вызывается заново,
+// поэтому результаты, вычисленные в fn, она должна каждый раз заполнять с нуля.
+func (s *PersonService) inTx(ctx context.Context, fn func(tx repository.Stores) error) error {
+	return s.transactions.WithTx(ctx, fn)
+}
+
+func newPerson(req, original models.CreatePersonRequest, enrichment *client.Enrichment, now time.Time) *models.Person {
 	person := &models.Person{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Surname:     req.Surname,
-		Patronymic:  req.Patronymic,
-		Age:         age,
-		Gender:      gender,
-		Nationality: nationality,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	err = s.repo.Create(ctx, *person)
+		ID:         uuid.New(),
+		Name:       req.Name,
+		Surname:    req.Surname,
+		Patronymic: req.Patronymic,
+
+		NameOriginal:       original.Name,
+		SurnameOriginal:    original.Surname,
+		PatronymicOriginal: original.Patronymic,
+
+		Age:         enrichment.Age,
+		Gender:      enrichment.Gender,
+		Nationality: enrichment.Nationality,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+
+		EnrichmentSources: enrichment.Sources,
+	}
+	if person.Age > 0 {
+		person.BirthYear = now.Year() - person.Age
+	}
+	return person
+}
+
+// Replace полностью заменяет редактируемые поля человека, приводя ФИО к каноническому виду.
+// version > 0 включает проверку, что запись не изменилась с момента чтения клиентом.
+func (s *PersonService) Replace(ctx context.Context, id uuid.UUID, version int, req models.CreatePersonRequest) (*models.Person, error) {
+	var person *models.Person
+	err := s.inTx(ctx, func(tx repository.Stores) error {
+		var err error
+		person, err = s.replace(ctx, tx.Persons, id, version, req, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+// replace - Replace, в котором поля, совпавшие с current, сохраняют прежние исходные значения.
+func (s *PersonService) replace(ctx context.Context, repo repository.PersonStore, id uuid.UUID, version int, req models.CreatePersonRequest, current *models.Person) (*models.Person, error) {
+	original := canonicalizeRequest(&req)
+	update := models.UpdatePersonRequest{
+		Name:       &req.Name,
+		Surname:    &req.Surname,
+		Patronymic: &req.Patronymic,
+
+		NameOriginal:       &original.Name,
+		SurnameOriginal:    &original.Surname,
+		PatronymicOriginal: &original.Patronymic,
+	}
+	if current != nil {
+		if req.Name == current.Name {
+			update.NameOriginal = &current.NameOriginal
+		}
+		if req.Surname == current.Surname {
+			update.SurnameOriginal = &current.SurnameOriginal
+		}
+		if req.Patronymic == current.Patronymic {
+			update.PatronymicOriginal = &current.PatronymicOriginal
+		}
+	}
+	if err := Validate(&update); err != nil {
+		return nil, err
+	}
+
+	person, err := repo.Update(ctx, id, version, update)
+	if err != nil {
+		return nil, err
+	}
+	person.Age = person.CurrentAge(time.Now())
+	return person, nil
+}
+
+// Patch применяет apply к документу с редактируемыми полями человека (формат CreatePersonRequest)
+// и сохраняет результат так же, как Replace; не затронутые патчем поля сохраняют исходные значения.
+// Чтение и запись выполняются в одной транзакции; запись сохраняется, только если не изменилась после чтения.
+func (s *PersonService) Patch(ctx context.Context, id uuid.UUID, version int, apply func(doc []byte) ([]byte, error)) (*models.Person, error) {
+	var person *models.Person
+	err := s.inTx(ctx, func(tx repository.Stores) error {
+		current, err := tx.Persons.GetByID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		if version > 0 && current.Version != version {
+			return repository.ErrVersionMismatch
+		}
+
+		doc, err := json.Marshal(models.CreatePersonRequest{
+			Name:       current.Name,
+			Surname:    current.Surname,
+			Patronymic: current.Patronymic,
+		})
+		if err != nil {
+			return err
+		}
+
+		patched, err := apply(doc)
+		if err != nil {
+			return err
+		}
+
+		var req models.CreatePersonRequest
+		dec := json.NewDecoder(bytes.NewReader(patched))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			return fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		person, err = s.replace(ctx, tx.Persons, id, current.Version, req, current)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+func (s *PersonService) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	return s.inTx(ctx, func(tx repository.Stores) error {
+		return tx.Persons.Delete(ctx, id, version)
+	})
+}
+
+func (s *PersonService) Restore(ctx context.Context, id uuid.UUID) (*models.Person, error) {
+	var person *models.Person
+	err := s.inTx(ctx, func(tx repository.Stores) error {
+		var err error
+		person, err = tx.Persons.Restore(ctx, id)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	person.Age = person.CurrentAge(time.Now())
+	return person, nil
+}
 
+// PurgeDeleted окончательно удаляет людей, удаленных раньше чем retention назад.
+func (s *PersonService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.Purge(ctx, time.Now().Add(-retention))
+}
+
+// GetByID возвращает человека; для поглощенного при слиянии - *MergedError с id выжившего.
+func (s *PersonService) GetByID(ctx context.Context, id uuid.UUID, includeDeleted bool) (*models.Person, error) {
+	person, err := s.repo.GetByID(ctx, id, includeDeleted)
+	if errors.Is(err, repository.ErrNotFound) {
+		into, mergedErr := s.repo.MergedInto(ctx, id)
+		if mergedErr == nil {
+			return nil, &MergedError{ID: id, Into: into}
+		}
+		if !errors.Is(mergedErr, repository.ErrNotFound) {
+			return nil, mergedErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	person.Age = person.CurrentAge(time.Now())
+	return person, nil
+}
+
+// GetAsOf возвращает человека в том виде, в каком он был на момент at.
+func (s *PersonService) GetAsOf(ctx context.Context, id uuid.UUID, at time.Time, includeDeleted bool) (*models.Person, error) {
+	person, err := s.repo.GetAsOf(ctx, id, at)
+	if err != nil {
+		return nil, err
+	}
+	if person.DeletedAt != nil && !includeDeleted {
+		return nil, repository.ErrNotFound
+	}
+	person.Age = person.CurrentAge(at)
 	return person, nil
 }
 
-func (s *PersonService) Update(ctx context.Context, id uuid.UUID, req models.UpdatePersonRequest) error {
-	return s.repo.Update(ctx, id, req)
+// ListPage возвращает страницу списка при пагинации курсорами.
+func (s *PersonService) ListPage(ctx context.Context, limit int, cursor *repository.Cursor, f filter.Filter, sort repository.Sort) (*models.PersonPage, error) {
+	page, err := s.repo.GetPage(ctx, limit, cursor, canonicalFilter(f), sort)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := &models.PersonPage{Items: page.Items}
+	for i := range result.Items {
+		result.Items[i].Age = result.Items[i].CurrentAge(now)
+	}
+	if page.Next != nil {
+		result.NextCursor = page.Next.Encode()
+	}
+	if page.Prev != nil {
+		result.PrevCursor = page.Prev.Encode()
+	}
+	return result, nil
 }
 
-func (s *PersonService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+func (s *PersonService) Count(ctx context.Context, f filter.Filter, estimate bool) (int64, bool, error) {
+	return s.repo.Count(ctx, canonicalFilter(f), estimate)
 }
 
-func (s *PersonService) GetByID(ctx context.Context, id uuid.UUID) (*models.Person, error) {
-	return s.repo.GetByID(ctx, id)
+// Search ищет людей по ФИО и подсвечивает совпавшие части полей.
+func (s *PersonService) Search(ctx context.Context, query string, limit int) ([]models.PersonSearchResult, error) {
+	results, err := s.repo.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := search.Tokens(query)
+	now := time.Now()
+	for i := range results {
+		res := &results[i]
+		res.Age = res.CurrentAge(now)
+		res.Highlights = map[string]string{}
+		for field, value := range map[string]string{"name": res.Name, "surname": res.Surname, "patronymic": res.Patronymic} {
+			if h := search.Highlight(value, tokens); h != "" {
+				res.Highlights[field] = h
+			}
+		}
+	}
+	return results, nil
+}
+
+// History возвращает историю изменений человека. Без includeDeleted история удаленного
+// или окончательно удаленного человека не отдается: ErrNotFound, как у GetByID.
+func (s *PersonService) History(ctx context.Context, id uuid.UUID, limit, offset int, includeDeleted bool) ([]models.PersonHistoryEntry, error) {
+	if !includeDeleted {
+		if _, err := s.repo.GetByID(ctx, id, false); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.History(ctx, id, limit, offset)
+}
+
+// Export передает fn людей под фильтрами в порядке sort с возрастом на текущий момент.
+func (s *PersonService) Export(ctx context.Context, f filter.Filter, sort repository.Sort, fn func(models.Person) error) error {
+	now := time.Now()
+	return s.repo.Export(ctx, canonicalFilter(f), sort, func(p models.Person) error {
+		p.Age = p.CurrentAge(now)
+		return fn(p)
+	})
 }
 
-func (s *PersonService) List(ctx context.Context, limit, offset int, filters map[string]string) ([]models.Person, error) {
-	return s.repo.GetAll(ctx, limit, offset, filters)
+func (s *PersonService) List(ctx context.Context, limit, offset int, f filter.Filter, sort repository.Sort) ([]models.Person, error) {
+	people, err := s.repo.GetAll(ctx, limit, offset, canonicalFilter(f), sort)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range people {
+		people[i].Age = people[i].CurrentAge(now)
+	}
+	return people, nil
 }
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"effective-mobile-task/internal/client"
+	"effective-mobile-task/internal/filter"
+	"effective-mobile-task/internal/models"
+	"effective-mobile-task/internal/repository"
+	"github.com/google/uuid"
+)
+
+var errEnrich = errors.New("enrichment unavailable")
+
+// fakeEnricher возвращает одинаковые данные для всех имен, кроме "Сбой".
+type fakeEnricher struct{}
+
+func (fakeEnricher) Enrich(q client.Query) (*client.Enrichment, error) {
+	if q.Name == "Сбой" {
+		return nil, errEnrich
+	}
+	return &client.Enrichment{Age: 30, Gender: "male", Nationality: "RU"}, nil
+}
+
+// newTestServices - PersonService и ImportService над хранилищами в памяти.
+func newTestServices(t *testing.T, uniqueNames bool, mode DuplicateMode) (*PersonService, *ImportService) {
+	t.Helper()
+	persons := repository.NewMemoryPersonRepository(uniqueNames)
+	imports := repository.NewMemoryImportRepository()
+	uow := repository.NewMemoryUnitOfWork(persons, imports, repository.NewMemoryIdempotencyRepository())
+	s := NewPersonService(persons, uow, fakeEnricher{})
+	s.Duplicates = DuplicatePolicy{Mode: mode}
+	return s, NewImportService(imports, s)
+}
+
+func newTestService(t *testing.T, uniqueNames bool, mode DuplicateMode) *PersonService {
+	t.Helper()
+	s, _ := newTestServices(t, uniqueNames, mode)
+	return s
+}
+
+func mustCreate(t *testing.T, s *PersonService, name, surname string) *models.Person {
+	t.Helper()
+	p, err := s.Create(context.Background(), models.CreatePersonRequest{Name: name, Surname: surname})
+	if err != nil {
+		t.Fatalf("Create(%s %s) error = %v", name, surname, err)
+	}
+	return p
+}
+
+func countPersons(t *testing.T, s *PersonService) int64 {
+	t.Helper()
+	n, _, err := s.Count(context.Background(), filter.Filter{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestPersonServiceCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		uniqueNames bool
+		mode        DuplicateMode
+		req         models.CreatePersonRequest
+		wantErr     error
+		wantDupOf   bool
+		want        models.Person
+		wantCount   int64
+	}{
+		{
+			name: "canonicalizes and enriches",
+			req:  models.CreatePersonRequest{Name: "  иван ", Surname: "ИВАНОВ-ПЕТРОВ"},
+			want: models.Person{
+				Name: "Иван", Surname: "Иванов-Петров", NameOriginal: "  иван ", SurnameOriginal: "ИВАНОВ-ПЕТРОВ",
+				Age: 30, Gender: "male", Nationality: "RU", Version: 1,
+			},
+			wantCount: 2,
+		},
+		{
+			name:      "invalid request",
+			req:       models.CreatePersonRequest{Name: "Иван", Surname: "  "},
+			wantErr:   ErrValidation,
+			wantCount: 1,
+		},
+		{
+			name:      "enrichment failure",
+			req:       models.CreatePersonRequest{Name: "сбой", Surname: "Петров"},
+			wantErr:   errEnrich,
+			wantCount: 1,
+		},
+		{
+			name:      "exact duplicate ignores case and spaces",
+			mode:      DuplicatesExact,
+			req:       models.CreatePersonRequest{Name: " петр ", Surname: "ПЕТРОВ"},
+			wantErr:   repository.ErrConflict,
+			wantDupOf: true,
+			wantCount: 1,
+		},
+		{
+			name:        "store conflict without duplicate search",
+			uniqueNames: true,
+			mode:        DuplicatesOff,
+			req:         models.CreatePersonRequest{Name: "петр", Surname: "петров"},
+			wantErr:     repository.ErrConflict,
+			wantDupOf:   true,
+			wantCount:   1,
+		},
+		{
+			name:      "same name is allowed without duplicate search",
+			mode:      DuplicatesOff,
+			req:       models.CreatePersonRequest{Name: "Петр", Surname: "Петров"},
+			want:      models.Person{Name: "Петр", Surname: "Петров", NameOriginal: "Петр", SurnameOriginal: "Петров", Age: 30, Gender: "male", Nationality: "RU", Version: 1},
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, tt.uniqueNames, tt.mode)
+			existing := mustCreate(t, s, "Петр", "Петров")
+
+			got, err := s.Create(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+				}
+				var dup *DuplicateError
+				if tt.wantDupOf && (!errors.As(err, &dup) || dup.Existing.ID != existing.ID) {
+					t.Errorf("Create() error = %v, want *DuplicateError with %s", err, existing.ID)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+				if got.ID == uuid.Nil || got.BirthYear == 0 {
+					t.Errorf("Create() = %+v, want id and birth year", got)
+				}
+				stored, err := s.GetByID(context.Background(), got.ID, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, p := range []*models.Person{got, stored} {
+					if p.Name != tt.want.Name || p.Surname != tt.want.Surname ||
+						p.NameOriginal != tt.want.NameOriginal || p.SurnameOriginal != tt.want.SurnameOriginal ||
+						p.Age != tt.want.Age || p.Gender != tt.want.Gender || p.Nationality != tt.want.Nationality ||
+						p.Version != tt.want.Version {
+						t.Errorf("person = %+v, want %+v", p, tt.want)
+					}
+				}
+			}
+			if n := countPersons(t, s); n != tt.wantCount {
+				t.Errorf("%d persons stored, want %d", n, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPersonServiceReplaceAndPatch(t *testing.T) {
+	patchDoc := func(doc string) func([]byte) ([]byte, error) {
+		return func([]byte) ([]byte, error) { return []byte(doc), nil }
+	}
+	tests := []struct {
+		name    string
+		update  func(s *PersonService, id uuid.UUID) (*models.Person, error)
+		wantErr error
+		want    models.Person
+	}{
+		{
+			name: "replace stores canonical values and new originals",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Replace(context.Background(), id, 1, models.CreatePersonRequest{Name: "иоанн", Surname: "иванов"})
+			},
+			want: models.Person{Name: "Иоанн", Surname: "Иванов", NameOriginal: "иоанн", SurnameOriginal: "иванов", Version: 2},
+		},
+		{
+			name: "replace with a stale version",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Replace(context.Background(), id, 2, models.CreatePersonRequest{Name: "Иоанн", Surname: "Иванов"})
+			},
+			wantErr: repository.ErrVersionMismatch,
+		},
+		{
+			name: "replace of an unknown person",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Replace(context.Background(), uuid.New(), 0, models.CreatePersonRequest{Name: "Иоанн", Surname: "Иванов"})
+			},
+			wantErr: repository.ErrNotFound,
+		},
+		{
+			name: "replace with invalid values",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Replace(context.Background(), id, 0, models.CreatePersonRequest{Name: "Иоанн", Surname: "R2-D2"})
+			},
+			wantErr: ErrValidation,
+		},
+		{
+			name: "patch keeps originals of unchanged fields",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Patch(context.Background(), id, 0, patchDoc(`{"name":"Иван","surname":"Иванов","patronymic":"петрович"}`))
+			},
+			want: models.Person{Name: "Иван", Surname: "Иванов", Patronymic: "Петрович", NameOriginal: " иван", SurnameOriginal: "ИВАНОВ", Version: 2},
+		},
+		{
+			name: "patch with a stale version",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Patch(context.Background(), id, 3, func([]byte) ([]byte, error) {
+					t.Error("apply must not be called")
+					return nil, nil
+				})
+			},
+			wantErr: repository.ErrVersionMismatch,
+		},
+		{
+			name: "patch adding an unknown field",
+			update: func(s *PersonService, id uuid.UUID) (*models.Person, error) {
+				return s.Patch(context.Background(), id, 1, patchDoc(`{"name":"Иван","surname":"Иванов","age":5}`))
+			},
+			wantErr: ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, false, DuplicatesExact)
+			created := mustCreate(t, s, " иван", "ИВАНОВ")
+
+			got, err := tt.update(s, created.ID)
+			stored, getErr := s.GetByID(context.Background(), created.ID, false)
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if stored.Version != 1 || stored.Name != created.Name {
+					t.Errorf("stored person changed after a failed update: %+v", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			for _, p := range []*models.Person{got, stored} {
+				if p.Name != tt.want.Name || p.Surname != tt.want.Surname || p.Patronymic != tt.want.Patronymic ||
+					p.NameOriginal != tt.want.NameOriginal || p.SurnameOriginal != tt.want.SurnameOriginal ||
+					p.Version != tt.want.Version {
+					t.Errorf("person = %+v, want %+v", p, tt.want)
+				}
+			}
+			if got.Age != 30 || got.Gender != "male" {
+				t.Errorf("enriched fields lost: %+v", got)
+			}
+		})
+	}
+}
+
+func TestPersonServiceDeleteAndRestore(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      func(created uuid.UUID) uuid.UUID
+		version int
+		wantErr error
+	}{
+		{name: "without version check", id: func(id uuid.UUID) uuid.UUID { return id }},
+		{name: "with current version", id: func(id uuid.UUID) uuid.UUID { return id }, version: 1},
+		{name: "with stale version", id: func(id uuid.UUID) uuid.UUID { return id }, version: 2, wantErr: repository.ErrVersionMismatch},
+		{name: "unknown person", id: func(uuid.UUID) uuid.UUID { return uuid.New() }, wantErr: repository.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService(t, false, DuplicatesExact)
+			created := mustCreate(t, s, "Иван", "Иванов")
+
+			err := s.Delete(ctx, tt.id(created.ID), tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Delete() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := s.GetByID(ctx, created.ID, false); err != nil {
+					t.Errorf("person is not available after a failed delete: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := s.GetByID(ctx, created.ID, false); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("GetByID() after delete error = %v, want ErrNotFound", err)
+			}
+			if p, err := s.GetByID(ctx, created.ID, true); err != nil || p.DeletedAt == nil {
+				t.Errorf("GetByID(includeDeleted) = %+v, %v; want the deleted person", p, err)
+			}
+			if err := s.Delete(ctx, created.ID, 0); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+			}
+
+			restored, err := s.Restore(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if restored.DeletedAt != nil || restored.Name != "Иван" {
+				t.Errorf("Restore() = %+v", restored)
+			}
+			if _, err := s.GetByID(ctx, created.ID, false); err != nil {
+				t.Errorf("GetByID() after restore error = %v", err)
+			}
+		})
+	}
+}
+
+func TestPersonServiceMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		version     int
+		sameID      bool
+		wantErr     error
+		wantName    string
+		wantSurname string
+	}{
+		{
+			name:        "survivor keeps its fields",
+			wantName:    "Иван",
+			wantSurname: "Иванов",
+		},
+		{
+			name:        "fields from the merged person",
+			fields:      map[string]string{"name": "newest"},
+			version:     1,
+			wantName:    "Иоанн",
+			wantSurname: "Иванов",
+		},
+		{
+			name:    "stale survivor version",
+			version: 2,
+			wantErr: repository.ErrVersionMismatch,
+		},
+		{
+			name:    "unknown merge field",
+			fields:  map[string]string{"email": "newest"},
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "survivor listed as merged",
+			sameID:  true,
+			wantErr: ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService(t, false, DuplicatesOff)
+			survivor := mustCreate(t, s, "Иван", "Иванов")
+			merged := mustCreate(t, s, "Иоанн", "Иванов")
+
+			req := models.MergePersonsRequest{SurvivorID: survivor.ID, MergedIDs: []uuid.UUID{merged.ID}, Fields: tt.fields}
+			if tt.sameID {
+				req.MergedIDs = []uuid.UUID{survivor.ID}
+			}
+			got, err := s.Merge(ctx, req, tt.version)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Merge() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := s.GetByID(ctx, merged.ID, false); err != nil {
+					t.Errorf("merged person is gone after a failed merge: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if got.ID != survivor.ID || got.Name != tt.wantName || got.Surname != tt.wantSurname {
+				t.Errorf("Merge() = %+v, want %s %s", got, tt.wantName, tt.wantSurname)
+			}
+			var merr *MergedError
+			if _, err := s.GetByID(ctx, merged.ID, false); !errors.As(err, &merr) || merr.Into != survivor.ID {
+				t.Errorf("GetByID(merged) error = %v, want *MergedError into %s", err, survivor.ID)
+			}
+			if n := countPersons(t, s); n != 1 {
+				t.Errorf("%d persons stored after merge, want 1", n)
+			}
+		})
+	}
+}
+
+func TestPersonServiceCreateBulk(t *testing.T) {
+	valid := models.CreatePersonRequest{Name: "Анна", Surname: "Смирнова"}
+	tests := []struct {
+		name        string
+		uniqueNames bool
+		mode        DuplicateMode
+		atomic      bool
+		reqs        []models.CreatePersonRequest
+		want        []string
+		wantCount   int64
+	}{
+		{
+			name: "best effort saves valid items",
+			reqs: []models.CreatePersonRequest{
+				valid,
+				{Name: "", Surname: "Петров"},
+				{Name: "анна", Surname: "СМИРНОВА"},
+				{Name: "Сбой", Surname: "Сидоров"},
+				{Name: "Ольга", Surname: "Орлова"},
+			},
+			want:      []string{models.BulkCreated, models.BulkValidationError, models.BulkDuplicate, models.BulkEnrichmentError, models.BulkCreated},
+			wantCount: 3,
+		},
+		{
+			name:      "atomic skips everything after an invalid item",
+			atomic:    true,
+			reqs:      []models.CreatePersonRequest{valid, {Name: "Петр", Surname: "123"}},
+			want:      []string{models.BulkSkipped, models.BulkValidationError},
+			wantCount: 1,
+		},
+		{
+			name:        "best effort reports items rejected by the store",
+			uniqueNames: true,
+			reqs:        []models.CreatePersonRequest{valid, {Name: "Петр", Surname: "Петров"}},
+			want:        []string{models.BulkCreated, models.BulkDuplicate},
+			wantCount:   2,
+		},
+		{
+			name:        "atomic rolls back items rejected by the store",
+			uniqueNames: true,
+			atomic:      true,
+			reqs:        []models.CreatePersonRequest{valid, {Name: "петр", Surname: "петров"}},
+			want:        []string{models.BulkSkipped, models.BulkDuplicate},
+			wantCount:   1,
+		},
+		{
+			name:      "best effort reports a duplicate of a stored person",
+			mode:      DuplicatesExact,
+			reqs:      []models.CreatePersonRequest{valid, {Name: " петр", Surname: "ПЕТРОВ"}},
+			want:      []string{models.BulkCreated, models.BulkDuplicate},
+			wantCount: 2,
+		},
+		{
+			name:      "atomic rolls back a duplicate of a stored person",
+			mode:      DuplicatesExact,
+			atomic:    true,
+			reqs:      []models.CreatePersonRequest{valid, {Name: "Петр", Surname: "Петров"}},
+			want:      []string{models.BulkSkipped, models.BulkDuplicate},
+			wantCount: 1,
+		},
+		{
+			name:      "atomic saves a valid batch",
+			atomic:    true,
+			reqs:      []models.CreatePersonRequest{valid, {Name: "Ольга", Surname: "Орлова"}},
+			want:      []string{models.BulkCreated, models.BulkCreated},
+			wantCount: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, tt.uniqueNames, tt.mode)
+			existing := mustCreate(t, s, "Петр", "Петров")
+
+			results, err := s.CreateBulk(context.Background(), tt.reqs, tt.atomic)
+			if err != nil {
+				t.Fatalf("CreateBulk() error = %v", err)
+			}
+			var statuses []string
+			for i, res := range results {
+				statuses = append(statuses, res.Status)
+				if res.Index != i {
+					t.Errorf("results[%d].Index = %d", i, res.Index)
+				}
+				if (res.Status == models.BulkCreated) != (res.Person != nil) {
+					t.Errorf("results[%d] = %+v: person must be set only for created items", i, res)
+				}
+				if res.Status == models.BulkDuplicate && res.DuplicateOf == nil && (res.ExistingID == nil || *res.ExistingID != existing.ID) {
+					t.Errorf("results[%d].ExistingID = %v, want %s", i, res.ExistingID, existing.ID)
+				}
+			}
+			if !slices.Equal(statuses, tt.want) {
+				t.Errorf("statuses = %v, want %v", statuses, tt.want)
+			}
+			if n := countPersons(t, s); n != tt.wantCount {
+				t.Errorf("%d persons stored, want %d", n, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPersonServiceHistory(t *testing.T) {
+	tests := []struct {
+		name           string
+		prepare        func(s *PersonService, id uuid.UUID)
+		includeDeleted bool
+		wantErr        error
+		wantEntries    int
+	}{
+		{
+			name:        "active person",
+			prepare:     func(*PersonService, uuid.UUID) {},
+			wantEntries: 1,
+		},
+		{
+			name:    "deleted person is hidden",
+			prepare: func(s *PersonService, id uuid.UUID) { s.Delete(context.Background(), id, 0) },
+			wantErr: repository.ErrNotFound,
+		},
+		{
+			name:           "deleted person with includeDeleted",
+			prepare:        func(s *PersonService, id uuid.UUID) { s.Delete(context.Background(), id, 0) },
+			includeDeleted: true,
+			wantEntries:    2,
+		},
+		{
+			name: "purged person is hidden",
+			prepare: func(s *PersonService, id uuid.UUID) {
+				s.Delete(context.Background(), id, 0)
+				s.PurgeDeleted(context.Background(), -time.Hour)
+			},
+			wantErr: repository.ErrNotFound,
+		},
+		{
+			name: "purged person with includeDeleted",
+			prepare: func(s *PersonService, id uuid.UUID) {
+				s.Delete(context.Background(), id, 0)
+				s.PurgeDeleted(context.Background(), -time.Hour)
+			},
+			includeDeleted: true,
+			wantEntries:    3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, false, DuplicatesOff)
+			p := mustCreate(t, s, "Иван", "Иванов")
+			tt.prepare(s, p.ID)
+
+			entries, err := s.History(context.Background(), p.ID, 10, 0, tt.includeDeleted)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("History() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(entries) != tt.wantEntries {
+				t.Errorf("History() returned %d entries, want %d", len(entries), tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestImportServiceDuplicates(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        DuplicateMode
+		wantCreated int
+		wantErrors  []int
+	}{
+		{name: "stored person is reported", mode: DuplicatesExact, wantCreated: 2, wantErrors: []int{2, 4}},
+		{name: "only repeats within the file without duplicate search", mode: DuplicatesOff, wantCreated: 3, wantErrors: []int{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, imports := newTestServices(t, false, tt.mode)
+			existing := mustCreate(t, s, "Петр", "Петров")
+
+			data := "name,surname\nпетр,ПЕТРОВ\nАнна,Смирнова\nанна,смирнова\nОльга,Орлова\n"
+			imp, err := imports.Create(ctx, ImportRequest{Format: models.ImportCSV, Data: []byte(data)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := imports.ProcessNext(ctx, time.Minute); !ok || err != nil {
+				t.Fatalf("ProcessNext() = %v, %v", ok, err)
+			}
+
+			got, errs, err := imports.Errors(ctx, imp.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != models.ImportCompleted || got.CreatedRows != tt.wantCreated || got.FailedRows != len(tt.wantErrors) {
+				t.Errorf("import = %s, %d created, %d failed; want completed, %d created, %d failed",
+					got.Status, got.CreatedRows, got.FailedRows, tt.wantCreated, len(tt.wantErrors))
+			}
+			var rows []int
+			for _, e := range errs {
+				rows = append(rows, e.Row)
+				if e.Row == 2 && !strings.Contains(e.Error, existing.ID.String()) {
+					t.Errorf("row 2 error = %q, want the id of the stored person", e.Error)
+				}
+			}
+			if !slices.Equal(rows, tt.wantErrors) {
+				t.Errorf("failed rows = %v, want %v", rows, tt.wantErrors)
+			}
+			if n := countPersons(t, s); n != int64(1+tt.wantCreated) {
+				t.Errorf("%d persons stored, want %d", n, 1+tt.wantCreated)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"effective-mobile-task/internal/models"
+)
+
+// FieldError - ошибка значения одного поля запроса.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError перечисляет все некорректные поля запроса.
+// Сравнивается с ErrValidation через errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return ErrValidation.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// add запоминает ошибку поля.
+func (e *ValidationError) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// err возвращает e, если есть ошибки полей, иначе nil.
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate проверяет строковые поля структуры *v по тегам validate и возвращает
+// *ValidationError со всеми нарушениями. Правила через запятую применяются по порядку,
+// у поля сообщается первое нарушенное:
+//
+//	trim      - убрать пробелы по краям (значение в v меняется)
+//	required  - непустое значение
+//	max=N     - не длиннее N символов
+//	name      - только буквы латиницы или кириллицы, пробелы, дефисы и апострофы
+//
+// Поля-указатели со значением nil не проверяются. Теги разбираются один раз на тип;
+// у запросов из models это происходит при старте, так что ошибка в теге не доживает до запроса.
+func Validate(v any) error {
+	rv := reflect.ValueOf(v).Elem()
+	fields, err := typeRules(rv.Type())
+	if err != nil {
+		return err
+	}
+	verr := &ValidationError{}
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if msg := applyRules(fv, f.rules); msg != "" {
+			verr.add(f.name, msg)
+		}
+	}
+	return verr.err()
+}
+
+// fieldRules - разобранный тег validate одного поля.
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+}
+
+type rule struct {
+	name string
+	max  int
+}
+
+// validatedTypes - запросы, теги которых проверяются при старте.
+var validatedTypes = []any{models.CreatePersonRequest{}, models.UpdatePersonRequest{}}
+
+// rulesCache - reflect.Type -> []fieldRules.
+var rulesCache sync.Map
+
+func init() {
+	for _, v := range validatedTypes {
+		if _, err := typeRules(reflect.TypeOf(v)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// typeRules разбирает теги validate структуры t и запоминает результат.
+func typeRules(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("validate: %s is not a struct", t)
+	}
+	var fields []fieldRules
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		kind := sf.Type.Kind()
+		if kind == reflect.Pointer {
+			kind = sf.Type.Elem().Kind()
+		}
+		if kind != reflect.String {
+			return nil, fmt.Errorf("validate: %s.%s: rules apply only to strings", t.Name(), sf.Name)
+		}
+		rules, err := parseRules(tag)
+		if err != nil {
+			return nil, fmt.Errorf("validate: %s.%s: %w", t.Name(), sf.Name, err)
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		fields = append(fields, fieldRules{index: i, name: name, rules: rules})
+	}
+	rulesCache.Store(t, fields)
+	return fields, nil
+}
+
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for _, s := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(s, "=")
+		r := rule{name: name}
+		switch name {
+		case "trim", "required", "name":
+		case "max":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid max %q", arg)
+			}
+			r.max = n
+		default:
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// applyRules применяет правила к строке fv и возвращает текст первого нарушения.
+func applyRules(fv reflect.Value, rules []rule) string {
+	for _, r := range rules {
+		s := fv.String()
+		switch r.name {
+		case "trim":
+			fv.SetString(strings.TrimSpace(s))
+		case "required":
+			if s == "" {
+				return "is required"
+			}
+		case "max":
+			if utf8.RuneCountInString(s) > r.max {
+				return fmt.Sprintf("must be at most %d characters", r.max)
+			}
+		case "name":
+			if s != "" && !isName(s) {
+				return "must contain only Latin or Cyrillic letters, spaces, hyphens and apostrophes"
+			}
+		}
+	}
+	return ""
+}
+
+// isName проверяет, что s начинается с буквы и состоит из букв латиницы или кириллицы
+// (в том числе с диакритикой), пробелов, дефисов и апострофов.
+func isName(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.In(r, unicode.Latin, unicode.Cyrillic):
+		case i > 0 && unicode.Is(unicode.Mn, r):
+		case i > 0 && (r == ' ' || r == '-' || r == '\'' || r == '’'):
+		default:
+			return false
+		}
+	}
+	return true
+}
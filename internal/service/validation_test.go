@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"effective-mobile-task/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	long := strings.Repeat("я", 151)
+
+	tests := []struct {
+		name    string
+		v       any
+		want    []FieldError
+		trimmed any
+	}{
+		{
+			name:    "valid request is trimmed",
+			v:       &models.CreatePersonRequest{Name: "  Иван ", Surname: "Д'Артаньян", Patronymic: "Jean-Luc"},
+			trimmed: &models.CreatePersonRequest{Name: "Иван", Surname: "Д'Артаньян", Patronymic: "Jean-Luc"},
+		},
+		{
+			name: "required fields",
+			v:    &models.CreatePersonRequest{Name: "   "},
+			want: []FieldError{{"name", "is required"}, {"surname", "is required"}},
+		},
+		{
+			name: "length counts characters, not bytes",
+			v:    &models.CreatePersonRequest{Name: strings.Repeat("я", 150), Surname: long},
+			want: []FieldError{{"surname", "must be at most 150 characters"}},
+		},
+		{
+			name: "name characters",
+			v:    &models.CreatePersonRequest{Name: "Ivan1", Surname: "-Ivanov", Patronymic: "Ivan\xff"},
+			want: []FieldError{
+				{"name", "must contain only Latin or Cyrillic letters, spaces, hyphens and apostrophes"},
+				{"surname", "must contain only Latin or Cyrillic letters, spaces, hyphens and apostrophes"},
+				{"patronymic", "must contain only Latin or Cyrillic letters, spaces, hyphens and apostrophes"},
+			},
+		},
+		{
+			name: "empty optional field is allowed",
+			v:    &models.CreatePersonRequest{Name: "Ivan", Surname: "Ivanov", Patronymic: " "},
+		},
+		{
+			name: "nil pointers are skipped",
+			v:    &models.UpdatePersonRequest{Surname: ptr("")},
+			want: []FieldError{{"surname", "is required"}},
+		},
+		{
+			name:    "pointer values are trimmed",
+			v:       &models.UpdatePersonRequest{Name: ptr(" Анна ")},
+			trimmed: &models.UpdatePersonRequest{Name: ptr("Анна")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.v)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+			} else {
+				var verr *ValidationError
+				if !errors.As(err, &verr) || !errors.Is(err, ErrValidation) {
+					t.Fatalf("Validate() = %v, want *ValidationError", err)
+				}
+				if !slices.Equal(verr.Fields, tt.want) {
+					t.Errorf("fields = %v, want %v", verr.Fields, tt.want)
+				}
+			}
+			if tt.trimmed != nil && !reflect.DeepEqual(tt.v, tt.trimmed) {
+				t.Errorf("value after Validate = %+v, want %+v", tt.v, tt.trimmed)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsBadTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"unknown rule", &struct {
+			Name string `json:"name" validate:"trim,email"`
+		}{}},
+		{"invalid max", &struct {
+			Name string `json:"name" validate:"max=ten"`
+		}{}},
+		{"not a string", &struct {
+			Age int `json:"age" validate:"required"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.v)
+			if err == nil || errors.Is(err, ErrValidation) {
+				t.Errorf("Validate() = %v, want a tag error", err)
+			}
+		})
+	}
+}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"slices"
 
+	"effective-mobile-task/internal/service"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func main() {
@@ -20,6 +23,7 @@ func main() {
 	}
 
 	action := flag.String("action", "up", "Migration action (up, down)")
+	uniqueNames := flag.Bool("unique-names", false, "Also apply optional migrations forbidding persons with the same name (UNIQUE_PERSON_NAMES=true)")
 	flag.Parse()
 
 	dbConfig := getDBConfig()
@@ -28,7 +32,7 @@ func main() {
 		log.Fatalf("Failed to ensure database exists: %v", err)
 	}
 
-	if err := runMigrations(dbConfig, *action); err != nil {
+	if err := runMigrations(dbConfig, *action, *uniqueNames); err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
 
@@ -98,40 +102,133 @@ func createDatabaseIfNotExists(cfg DBConfig) error {
 	return nil
 }
 
-func runMigrations(cfg DBConfig, action string) error {
+// runMigrations применяет основные миграции, а с optional - еще и необязательные из
+// internal/migrations/optional. Их версии хранятся в отдельной таблице, поэтому
+// нумерация двух наборов не пересекается; откатываются они первыми.
+// После применения основных миграций ФИО приводятся к каноническому виду (canonicalizeNames).
+func runMigrations(cfg DBConfig, action string, optional bool) error {
 	db, err := sql.Open("postgres", cfg.ConnectionString())
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer db.Close()
 
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
-	}
-
 	migrationsPath := flag.String("migrations", "internal/migrations", "Path to migrations directory")
 	flag.Parse()
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+*migrationsPath,
-		"postgres", driver)
-	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+	sets := []struct{ path, table string }{{*migrationsPath, ""}}
+	if optional {
+		sets = append(sets, struct{ path, table string }{*migrationsPath + "/optional", "schema_migrations_optional"})
+	}
+	if action == "down" {
+		slices.Reverse(sets)
 	}
 
-	switch action {
-	case "up":
-		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			return fmt.Errorf("failed to apply migrations: %w", err)
+	for _, set := range sets {
+		driver, err := postgres.WithInstance(db, &postgres.Config{MigrationsTable: set.table})
+		if err != nil {
+			return fmt.Errorf("failed to create migration driver: %w", err)
 		}
-	case "down":
-		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			return fmt.Errorf("failed to rollback migrations: %w", err)
+
+		m, err := migrate.NewWithDatabaseInstance(
+			"file://"+set.path,
+			"postgres", driver)
+		if err != nil {
+			return fmt.Errorf("failed to create migrate instance: %w", err)
+		}
+
+		switch action {
+		case "up":
+			if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+				return fmt.Errorf("failed to apply migrations from %s: %w", set.path, err)
+			}
+			if set.table == "" {
+				if err := canonicalizeNames(db); err != nil {
+					return fmt.Errorf("failed to canonicalize person names: %w", err)
+				}
+			}
+		case "down":
+			if err := m.Down(); err != nil && err != migrate.ErrNoChange {
+				return fmt.Errorf("failed to rollback migrations from %s: %w", set.path, err)
+			}
+		default:
+			return fmt.Errorf("unknown action: %s", action)
 		}
-	default:
-		return fmt.Errorf("unknown action: %s", action)
 	}
 
 	return nil
 }
+
+// canonicalizeBatch - сколько людей читается за один запрос canonicalizeNames.
+const canonicalizeBatch = 1000
+
+// canonicalizeNames приводит ФИО людей, сохраненных до миграции 000013, к каноническому виду
+// service.CanonicalName и service.CanonicalSurname: в SQL правила регистра с частицами не
+// повторить. Меняются только строки с неканоническими ФИО, поэтому повторный запуск безопасен;
+// версия и история не меняются, исходные значения остаются в *_original.
+// Строки, которые после приведения совпали бы с другим человеком при UNIQUE_PERSON_NAMES,
+// пропускаются - их нужно объединить и запустить миграцию еще раз.
+func canonicalizeNames(db *sql.DB) error {
+	var (
+		lastID              = "00000000-0000-0000-0000-000000000000"
+		updated, conflicted int
+	)
+	for {
+		rows, err := db.Query(`
+			SELECT id, name, surname, patronymic FROM persons
+			WHERE id > $1 ORDER BY id LIMIT $2`, lastID, canonicalizeBatch)
+		if err != nil {
+			return err
+		}
+		type person struct {
+			id            string
+			name, surname string
+			patronymic    sql.NullString
+		}
+		var batch []person
+		for rows.Next() {
+			var p person
+			if err := rows.Scan(&p.id, &p.name, &p.surname, &p.patronymic); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].id
+
+		for _, p := range batch {
+			name := service.CanonicalName(p.name)
+			surname := service.CanonicalSurname(p.surname)
+			patronymic := p.patronymic
+			if patronymic.Valid {
+				patronymic.String = service.CanonicalName(patronymic.String)
+			}
+			if name == p.name && surname == p.surname && patronymic == p.patronymic {
+				continue
+			}
+			_, err := db.Exec(`UPDATE persons SET name = $2, surname = $3, patronymic = $4 WHERE id = $1`,
+				p.id, name, surname, patronymic)
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				log.Printf("Person %s not canonicalized: %q %q would duplicate another person", p.id, name, surname)
+				conflicted++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("person %s: %w", p.id, err)
+			}
+			updated++
+		}
+	}
+	if updated > 0 || conflicted > 0 {
+		log.Printf("Canonicalized names of %d persons, %d skipped as duplicates\n", updated, conflicted)
+	}
+	return nil
+}